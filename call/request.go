@@ -9,6 +9,7 @@ import (
 	"context"
 	"net/http"
 	"encoding/json/v2"
+	"github.com/clarkk/go-util/serv/req"
 )
 
 const (
@@ -70,6 +71,8 @@ func (c *Client) Send(ctx context.Context, uri string, in, out any, opts ...Opti
 		req.Header.Set(CONTENT_TYPE, TYPE_JSON)
 	}
 	
+	forward_request_ID(ctx, req)
+	
 	for _, opt := range c.options {
 		opt(req)
 	}
@@ -143,6 +146,13 @@ func (c *Client) request(req *http.Request, out any) (int, http.Header, error){
 	return status, header, nil
 }
 
+//	Forward request ID from the incoming request context
+func forward_request_ID(ctx context.Context, r *http.Request){
+	if id := req.Context_request_ID(ctx); id != "" {
+		r.Header.Set(req.REQUEST_ID, id)
+	}
+}
+
 func (c *Client) payload(in any) (string, io.Reader, error){
 	if in == nil {
 		return http.MethodGet, nil, nil
@@ -1,6 +1,6 @@
 module github.com/clarkk/go-util
 
-go 1.26.0
+go 1.27.0
 
 require (
 	github.com/go-errors/errors v1.5.1
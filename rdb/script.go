@@ -0,0 +1,22 @@
+package rdb
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+type Script struct {
+	script		*redis.Script
+}
+
+//	Create Lua script (loaded and cached on the server by its SHA1 hash on first run)
+func NewScript(src string) *Script {
+	return &Script{
+		script: redis.NewScript(src),
+	}
+}
+
+//	Run script atomically and fetch integer array reply
+func (s *Script) Int64s(ctx context.Context, keys []string, args ...any) ([]int64, error){
+	return s.script.Run(ctx, client, keys, args...).Int64Slice()
+}
\ No newline at end of file
@@ -6,8 +6,9 @@ import (
 	"log"
 	"fmt"
 	"time"
-	"sync"
+	"slices"
 	"regexp"
+	"strconv"
 	"strings"
 	"context"
 	"runtime"
@@ -15,23 +16,42 @@ import (
 	"net/http"
 	"github.com/go-errors/errors"
 	"github.com/clarkk/go-util/cmd"
+	"github.com/clarkk/go-util/serv/req"
 )
 
-const ctx_slug ctx_key = ""
+const (
+	ctx_slug ctx_key 	= ""
+	ctx_param ctx_key 	= "param"
+	ctx_route ctx_key 	= "route"
+	ctx_label ctx_key 	= "label"
+	ctx_subhost ctx_key = "subhost"
+	ctx_errors ctx_key 	= "errors"
+	ctx_nonce ctx_key 	= "nonce"
+)
 
 var (
 	re_sld			= regexp.MustCompile(`^[a-z0-9]+(?:[a-z0-9-]*[a-z0-9]+)?\.$`)
+	re_sld_wildcard	= regexp.MustCompile(`^\*\.(?:[a-z0-9]+(?:[a-z0-9-]*[a-z0-9]+)?\.)?$`)
 	re_path_prefix	= regexp.MustCompile(`^/[a-z]+$`)
 )
 
 type (
 	HTTP struct {
 		tld 		string
-		tld_len 	int
+		tlds 		[]string
 		listen_ip 	string
 		listen_port int
 		test		bool
 		subhosts 	subhosts
+		adapters	[]Adapter
+		tls			*tls_cert
+		options		Options
+		shutdown_hooks	[]Shutdown_hook
+		metrics		*Metrics
+		access_log	*access_logger
+		//	Invalid or duplicate subhosts
+		detached	[]*Subhost
+		errs		[]error
 	}
 	
 	subhosts 		map[string]*Subhost
@@ -44,23 +64,35 @@ func NewHTTP(tld, listen_ip string, listen_port int) *HTTP {
 	
 	return &HTTP{
 		tld:			tld,
-		tld_len:		len(tld),
+		tlds:			[]string{tld},
 		listen_ip:		listen_ip,
 		listen_port:	listen_port,
 		subhosts:		subhosts{},
+		options:		Default_options(),
 	}
 }
 
 //	Recover from panic inside route handler
 func Recover(w http.ResponseWriter){
 	if err := recover(); err != nil {
-		if rw, ok := w.(*Writer); ok && !rw.Sent_header() {
-			http.Error(w, "Unexpected error", http.StatusInternalServerError)
-		}
-		log.Println(errors.Wrap(err, 2).ErrorStack())
+		log.Println(recover_error(w, err))
+	}
+}
+
+//	Recover from panic inside route handler and log the stack trace with the request ID
+func Recover_request(w http.ResponseWriter, r *http.Request){
+	if err := recover(); err != nil {
+		log.Printf("Request ID %s: %s", req.Request_ID(r), recover_error(w, err))
 	}
 }
 
+func recover_error(w http.ResponseWriter, err any) string {
+	if rw, ok := w.(*Writer); ok && !rw.Sent_header() {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+	}
+	return errors.Wrap(err, 3).ErrorStack()
+}
+
 func Get_slug(r *http.Request, index int) string {
 	value := r.Context().Value(ctx_slug)
 	if value == nil {
@@ -73,11 +105,37 @@ func Get_slug(r *http.Request, index int) string {
 	return fields[index]
 }
 
+//	Get named route parameter: /user/:id<int> -> Param(r, "id")
+func Param(r *http.Request, name string) string {
+	return Context_param(r.Context(), name)
+}
+
+//	Get named route parameter of type int (returns 0 if not found)
+func Param_int(r *http.Request, name string) int {
+	i, err := strconv.Atoi(Param(r, name))
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+//	Get route pattern matched by the request
+func Route_pattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(ctx_route).(string)
+	return pattern
+}
+
 func (h *HTTP) Test(){
 	cmd.Out("HTTP server in test mode")
 	h.test = true
 }
 
+//	Apply middleware to all routes on all subhosts (executed in the order applied)
+func (h *HTTP) Use(adapters ...Adapter) *HTTP {
+	h.adapters = append(h.adapters, adapters...)
+	return h
+}
+
 //	Apply subhost with underlying routes
 func (h *HTTP) Subhost(sld string) *Subhost {
 	return h.Subhost_path_prefix(sld, "")
@@ -86,35 +144,76 @@ func (h *HTTP) Subhost(sld string) *Subhost {
 //	Apply subhost with underlying routes and path prefix
 func (h *HTTP) Subhost_path_prefix(sld, path_prefix string) *Subhost {
 	//	Validate subhost (sub-level domain)
-	if !re_sld.MatchString(sld) {
-		if sld[len(sld)-1:] != "." {
-			log.Fatalf("Subhost must end with '.': %s -> %s.", sld, sld)
+	if !re_sld.MatchString(sld) && !re_sld_wildcard.MatchString(sld) {
+		switch {
+		case sld == "":
+			h.add_error(fmt.Errorf("Subhost can not be empty (use Apex() for the apex domain)"))
+		case sld[len(sld)-1:] != ".":
+			h.add_error(fmt.Errorf("Subhost must end with '.': %s -> %s.", sld, sld))
+		default:
+			h.add_error(fmt.Errorf("Subhost must only contain a-z and '-' (or start with '*.' as wildcard): %s", sld))
 		}
-		log.Fatalf("Subhost must only contain a-z and '-': %s", sld)
+		return h.detached_subhost(sld, path_prefix)
 	}
+	return h.subhost(sld, path_prefix)
+}
+
+//	Invalid subhosts are not served but returned so routes can still be applied
+func (h *HTTP) subhost(sld, path_prefix string) *Subhost {
 	if _, ok := h.subhosts[sld]; ok {
-		log.Fatalf("Subhost already exists: %s", sld)
+		h.add_error(fmt.Errorf("Subhost already exists: %s", sld))
+		return h.detached_subhost(sld, path_prefix)
 	}
 	//	Validate path prefix
 	if path_prefix != "" {
 		if !re_path_prefix.MatchString(path_prefix) {
 			if path_prefix[0] != '/' {
-				log.Fatalf("Path prefix must start with '/': %s -> /%s", path_prefix, path_prefix)
+				h.add_error(fmt.Errorf("Path prefix must start with '/': %s -> /%s", path_prefix, path_prefix))
+			} else {
+				h.add_error(fmt.Errorf("Path prefix contains invalid chars: %s", path_prefix))
 			}
-			log.Fatalf("Path prefix contains invalid chars")
+			return h.detached_subhost(sld, path_prefix)
 		}
 	}
-	h.subhosts[sld] = &Subhost{
+	h.subhosts[sld] = newSubhost(sld, path_prefix)
+	return h.subhosts[sld]
+}
+
+//	Subhost not served but kept so errors of its routes are still returned by Validate
+func (h *HTTP) detached_subhost(sld, path_prefix string) *Subhost {
+	s := newSubhost(sld, path_prefix)
+	h.detached = append(h.detached, s)
+	return s
+}
+
+func newSubhost(sld, path_prefix string) *Subhost {
+	return &Subhost{
+		sld:			sld,
 		path_prefix:	path_prefix,
 		map_routes:		map_routes{},
 		map_exact:		map_exact{},
 		routes:			routes{},
+		router:			newRouter(),
 	}
-	return h.subhosts[sld]
 }
 
-//	Start server
+//	Collect registration error (returned by Validate)
+func (h *HTTP) add_error(err error){
+	h.errs = append(h.errs, err)
+}
+
+//	Start server and shutdown gracefully on SIGINT/SIGTERM (stop accepting new connections/requests): CTRL+C or "kill -INT $pid"
 func (h *HTTP) Run(){
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
+	if err := h.Run_context(ctx); err != nil {
+		log.Fatalf("HTTP server: %s", err)
+	}
+}
+
+//	Start server and shutdown gracefully when the context is done
+func (h *HTTP) Run_context(ctx context.Context) error {
 	h.output_init()
 	
 	for sld, s := range h.subhosts {
@@ -124,151 +223,162 @@ func (h *HTTP) Run(){
 		}
 	}
 	
-	srv := &http.Server{
-		Addr:				fmt.Sprintf("%s:%d", h.listen_ip, h.listen_port),
-		Handler:			http.HandlerFunc(h.serve),
-		//ReadTimeout:		5 * time.Second,
-		ReadHeaderTimeout:	100 * time.Millisecond,
-		//WriteTimeout:		10 * time.Second,
-		IdleTimeout:		30 * time.Second,
+	if err := h.Validate(); err != nil {
+		return err
 	}
 	
-	wait := &sync.WaitGroup{}
-	wait.Add(1)
+	srv := h.new_server()
+	
+	tls_quit := make(chan struct{})
+	defer close(tls_quit)
+	
+	if h.tls_enabled() {
+		if err := h.load_tls(); err != nil {
+			return fmt.Errorf("TLS: %w", err)
+		}
+		srv.TLSConfig = h.tls_config()
+		go h.watch_tls(tls_quit)
+	}
 	
+	serve_err := make(chan error, 1)
 	go func(){
-		defer wait.Done()
-		
-		//	Always returns http.ErrServerClosed on SIGINT
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			//	Server stopped unexpectedly
-			if pid, name := h.used_port_pid(); pid != "" {
-				log.Fatalf("Port %d is already in use by PID %s %s",
-					h.listen_port,
-					pid,
-					name,
-				)
-			}
-			log.Fatalf("HTTP server: %s", err)
+		//	Always returns http.ErrServerClosed on shutdown
+		err := h.listen_and_serve(srv)
+		if err == http.ErrServerClosed {
+			cmd.Out("HTTP server stopped serving")
+			err = nil
 		}
-		
-		cmd.Out("HTTP server stopped serving")
+		serve_err <- err
 	}()
 	
-	//	Listening for SIGINT to shutdown gracefully (stop accepting new connections/requests): CTRL+C or "kill -INT $pid"
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	select {
+	case err := <-serve_err:
+		//	Server stopped unexpectedly
+		if pid, name := h.used_port_pid(); pid != "" {
+			return fmt.Errorf("Port %d is already in use by PID %s %s: %w",
+				h.listen_port,
+				pid,
+				name,
+				err,
+			)
+		}
+		return err
+	case <-ctx.Done():
+	}
 	
-	cmd.Out("HTTP server received SIGINT to shutdown gracefully")
+	cmd.Out("HTTP server received signal to shutdown gracefully")
 	
-	ctx, cancel := context.WithTimeout(context.Background(), 30 * time.Second)
+	shutdown_ctx, cancel := h.options.shutdown_context()
 	defer cancel()
 	
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("HTTP server shutdown: %s", err)
+	var errs []error
+	if err := srv.Shutdown(shutdown_ctx); err != nil {
+		errs = append(errs, fmt.Errorf("Shutdown: %w", err))
+	}
+	if err := <-serve_err; err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, h.run_shutdown_hooks(shutdown_ctx)...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
-	
-	wait.Wait()
 	
 	cmd.Out("HTTP server shutdown completed successfully")
+	return nil
+}
+
+func (h *HTTP) listen_and_serve(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		//	Certificates are picked by TLSConfig.GetCertificate
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
 }
 
 //	Subhost and route pattern handler
 func (h *HTTP) serve(w http.ResponseWriter, r *http.Request){
-	w = NewWriter(w)
-	defer Recover(w)
+	rw := NewWriter(w)
+	//	Discard response body on HEAD requests
+	rw.discard_body = r.Method == http.MethodHead
+	w = rw
 	
-	if !strings.HasSuffix(r.Host, h.tld) || r.Host == h.tld {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		log.Printf("Unsupported host (TLD %s): %s", h.tld, r.Host)
-		return
+	//	Accept or generate request ID and echo it in the response
+	request_id := req.Accept_request_ID(r)
+	w.Header().Set(req.REQUEST_ID, request_id)
+	r = r.WithContext(req.With_request_ID(r.Context(), request_id))
+	defer Recover_request(w, r)
+	
+	//	Log all responses after recovering from panic
+	var subhost, pattern string
+	if h.access_log != nil {
+		defer h.access_log.observe(rw, r, &subhost, &pattern)()
 	}
 	
-	sld 	:= r.Host[:len(r.Host)-h.tld_len]
-	s, ok 	:= h.subhosts[sld]
-	if !ok {
+	s, label, err := h.resolve_subhost(r.Host)
+	if err != nil {
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		log.Printf("Unsupported subhost (SLD %s): %s", sld, r.Host)
+		log.Println(err)
 		return
 	}
+	subhost = s.sld
+	
+	//	Observe metrics after recovering from panic
+	if h.metrics != nil {
+		defer h.metrics.observe(w.(*Writer), s.sld, &pattern)()
+	}
+	
+	defer s.recover_panic(w, r)
 	
 	ctx 	:= r.Context()
 	path 	:= strip_trailing_slash(r.URL.Path)
 	
+	ctx = context.WithValue(ctx, ctx_subhost, s.sld)
+	ctx = context.WithValue(ctx, ctx_errors, s)
+	if label != "" {
+		ctx = context.WithValue(ctx, ctx_label, label)
+	}
+	
 	var match_route *route_handler
-	for _, route := range s.routes {
-		//	Regex pattern
-		if route.regex != nil {
-			matches := route.regex.FindStringSubmatch(path)
-			len 	:= len(matches)
-			if len > 0 {
-				if route.depth != 0 && !match_path_depth(path, matches[0]) {
-					continue
-				}
-				
-				handler, ok := match_method(route, w, r)
-				if !ok {
-					return
-				}
-				
-				//	Slug group capture
-				if len > 1 {
-					ctx = context.WithValue(ctx, ctx_slug, matches[1:])
-				}
-				
-				match_route = handler
-				break
-			}
-		//	Path
-		} else {
-			if match_path(path, route) {
-				handler, ok := match_method(route, w, r)
-				if !ok {
-					return
-				}
-				match_route = handler
-				break
-			}
+	if route, slugs := s.router.match(path); route != nil {
+		pattern = route.pattern
+		
+		//	Answer CORS preflight request
+		if s.preflight(route, w, r) {
+			return
 		}
+		
+		handler, ok := s.match_method(route, w, r)
+		if !ok {
+			return
+		}
+		handler.cors(s).allow_origin(w, r)
+		
+		//	Slug group capture
+		if len(slugs) > 0 {
+			ctx = context.WithValue(ctx, ctx_slug, slugs)
+			ctx = context.WithValue(ctx, ctx_param, route.params)
+		}
+		
+		ctx = context.WithValue(ctx, ctx_route, route.pattern)
+		match_route = handler
 	}
 	
 	//	Return HTTP 404 if no route was matched or route is blind
 	if match_route == nil || match_route.blind {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		s.error(w, r, http.StatusNotFound, nil)
 		return
 	}
 	
-	//	Apply timeout context
+	handler := match_route.handler_chain(h.adapters, s.adapters)
+	
+	//	Apply timeout
 	if match_route.timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(match_route.timeout) * time.Second)
-		defer cancel()
-		
-		done := make(chan struct{})
-		go func(){
-			defer Recover(w)
-			
-			//	Serve HTTP request to client
-			match_route.handler(w, r.WithContext(ctx))
-			close(done)
-		}()
-		
-		//	Wait until the context is done/canceled/timeout
-		select {
-		case <-done:
-			//	Request finished normally
-		case <-ctx.Done():
-			//	Return HTTP 408 Timeout if request reached timeout
-			if ctx.Err() == context.DeadlineExceeded {
-				if rw, ok := w.(*Writer); ok && !rw.Sent_header() {
-					http.Error(w, http.StatusText(http.StatusRequestTimeout), http.StatusRequestTimeout)
-				}
-			}
+		if s.serve_timeout(handler, w, r.WithContext(ctx), time.Duration(match_route.timeout) * time.Second) {
+			h.metrics.timeout(s.sld, pattern)
 		}
 	} else {
 		//	Serve HTTP request to client
-		match_route.handler(w, r.WithContext(ctx))
+		handler(w, r.WithContext(ctx))
 	}
 }
 
@@ -288,12 +398,16 @@ func (h *HTTP) output_init(){
 		}
 	}
 	
+	if h.tls_enabled() {
+		cmd.Outf("HTTPS with TLS certificates: %d\n", len(h.tls_certs()))
+	}
+	
 	usr, _ := cmd.Get_user()
 	
 	cmd.Outf("Listening on: %s:%d, TLD: %s (PID: %d, GOMAXPROCS: %d) running as '%s'\n",
 		h.listen_ip,
 		h.listen_port,
-		h.tld,
+		strings.Join(h.tlds, ", "),
 		os.Getpid(),
 		runtime.GOMAXPROCS(0),
 		usr.Username,
@@ -326,42 +440,60 @@ func (h *HTTP) used_port_pid() (string, string){
 	return "", ""
 }
 
-func match_method(route *route, w http.ResponseWriter, r *http.Request) (*route_handler, bool){
+func (s *Subhost) match_method(route *route, w http.ResponseWriter, r *http.Request) (*route_handler, bool){
 	if handler, ok := route.methods[string(ALL)]; ok {
 		return handler, true
 	}
 	
-	handler, ok := route.methods[r.Method]
-	if !ok {
-		//	Return HTTP 405 if no route was matched
-		methods := make([]string, len(route.methods))
-		i := 0
-		for k := range route.methods {
-			methods[i] = k
-			i++
+	if handler, ok := route.methods[r.Method]; ok {
+		return handler, true
+	}
+	
+	//	Answer HEAD with GET route (the response body is discarded)
+	if r.Method == http.MethodHead {
+		if handler, ok := route.methods[http.MethodGet]; ok {
+			return handler, true
 		}
-		w.Header().Set("Allow", strings.Join(methods, ", "))
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+	
+	//	Return HTTP 404 if all methods on route are blind
+	allow := route.allow()
+	if allow == "" {
+		s.error(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	w.Header().Set("Allow", allow)
+	
+	//	Answer OPTIONS with the methods allowed on route
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
 		return nil, false
 	}
-	return handler, true
+	
+	//	Return HTTP 405 if no route was matched
+	s.error(w, r, http.StatusMethodNotAllowed, nil)
+	return nil, false
 }
 
-func match_path(path string, route *route) bool {
-	if route.exact {
-		return path == route.pattern
+//	Get sorted list of methods allowed on route (HEAD with GET and OPTIONS are answered automatically)
+func (route *route) allow() string {
+	var methods []string
+	for method, handler := range route.methods {
+		if !handler.blind {
+			methods = append(methods, method)
+		}
 	}
-	if !strings.HasPrefix(path, route.pattern) {
-		return false
+	if len(methods) == 0 {
+		return ""
 	}
-	if route.depth != 0 {
-		return match_path_depth(path, route.pattern)
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
 	}
-	return true
-}
-
-func match_path_depth(path, pattern string) bool {
-	return path == pattern || path[len(pattern)] == '/'
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
 }
 
 func strip_trailing_slash(url string) string {
@@ -0,0 +1,144 @@
+package serv
+
+import (
+	"io"
+	"fmt"
+	"log"
+	"time"
+	"slices"
+	"strconv"
+	"net/http"
+	"math/rand/v2"
+	"encoding/json/v2"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+const (
+	LOG_COMMON Log_format 	= iota
+	LOG_COMBINED
+	LOG_JSON
+	
+	clf_time_format 		= "02/Jan/2006:15:04:05 -0700"
+)
+
+type (
+	Log_format int
+	
+	Access_log_options struct {
+		Format		Log_format
+		//	Fraction of requests to log: 0.1 = 10% (0 logs all requests)
+		Sample		float64
+		//	Paths excluded from logging (e.g. health checks)
+		Exclude		[]string
+	}
+	
+	access_log_entry struct {
+		Time		string	`json:"time"`
+		Request_ID	string	`json:"request_id"`
+		Client_IP	string	`json:"client_ip"`
+		User		string	`json:"user,omitempty"`
+		Method		string	`json:"method"`
+		URI			string	`json:"uri"`
+		Proto		string	`json:"proto"`
+		Status		int		`json:"status"`
+		Bytes		int		`json:"bytes"`
+		Duration	float64	`json:"duration_ms"`
+		Subhost		string	`json:"subhost"`
+		Route		string	`json:"route"`
+		Referer		string	`json:"referer,omitempty"`
+		User_agent	string	`json:"user_agent,omitempty"`
+		start		time.Time
+	}
+	
+	access_logger struct {
+		out			io.Writer
+		opts		Access_log_options
+	}
+)
+
+/*
+	Access log writing lines directly to the logger output (e.g. rotating file from logs.New)
+	
+	All responses are logged including unknown hosts, HTTP 404, HTTP 405 and automatic HEAD/OPTIONS responses.
+	Common and combined log format are followed by duration (ms), subhost and route pattern:
+	127.0.0.1 - - [18/Oct/2026:09:00:00 +0000] "GET /user/7 HTTP/1.1" 200 512 "-" "curl/8.0" 1.204 "api." "/user/:id<int>"
+*/
+func (h *HTTP) Access_log(logger *log.Logger, opts Access_log_options) *HTTP {
+	h.access_log = &access_logger{
+		out:	logger.Writer(),
+		opts:	opts,
+	}
+	return h
+}
+
+//	Returns function writing the log line when the response is served (subhost and pattern are resolved while serving)
+func (l *access_logger) observe(w *Writer, r *http.Request, subhost, pattern *string) func(){
+	if slices.Contains(l.opts.Exclude, r.URL.Path) || (l.opts.Sample > 0 && rand.Float64() >= l.opts.Sample) {
+		return func(){}
+	}
+	start := time.Now()
+	return func(){
+		entry := newAccess_log_entry(w, r, start, *subhost, *pattern)
+		if _, err := l.out.Write(entry.format(l.opts.Format)); err != nil {
+			log.Printf("Access log: %v", err)
+		}
+	}
+}
+
+func newAccess_log_entry(w *Writer, r *http.Request, start time.Time, subhost, pattern string) *access_log_entry {
+	user, _, _ := r.BasicAuth()
+	return &access_log_entry{
+		Time:		start.Format(time.RFC3339),
+		Request_ID:	req.Request_ID(r),
+		Client_IP:	req.Get_client_IP(r),
+		User:		user,
+		Method:		r.Method,
+		URI:		r.URL.RequestURI(),
+		Proto:		r.Proto,
+		Status:		w.Status(),
+		Bytes:		w.Sent(),
+		Duration:	float64(time.Since(start).Microseconds()) / 1000,
+		Subhost:	subhost,
+		Route:		pattern,
+		Referer:	r.Referer(),
+		User_agent:	req.User_agent(r),
+		start:		start,
+	}
+}
+
+func (e *access_log_entry) format(format Log_format) []byte {
+	if format == LOG_JSON {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return []byte(fmt.Sprintf("{\"error\":%q}\n", err.Error()))
+		}
+		return append(b, '\n')
+	}
+	
+	line := fmt.Sprintf("%s - %s [%s] %s %d %s",
+		e.Client_IP,
+		clf_value(e.User),
+		e.start.Format(clf_time_format),
+		strconv.Quote(e.Method+" "+e.URI+" "+e.Proto),
+		e.Status,
+		clf_bytes(e.Bytes),
+	)
+	if format == LOG_COMBINED {
+		line += " "+strconv.Quote(clf_value(e.Referer))+" "+strconv.Quote(clf_value(e.User_agent))
+	}
+	return fmt.Appendf(nil, "%s %.3f %s %s\n", line, e.Duration, strconv.Quote(e.Subhost), strconv.Quote(e.Route))
+}
+
+func clf_value(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func clf_bytes(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
\ No newline at end of file
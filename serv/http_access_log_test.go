@@ -0,0 +1,98 @@
+package serv
+
+import (
+	"log"
+	"fmt"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"encoding/json/v2"
+)
+
+func Test_access_log(t *testing.T){
+	var buf bytes.Buffer
+	
+	test_serve := func(format Log_format, method, url string){
+		buf.Reset()
+		
+		h := NewHTTP(tld, "", 0).
+			Access_log(log.New(&buf, "", log.LstdFlags), Access_log_options{
+				Format:		format,
+				Exclude:	[]string{"/health"},
+			})
+		h.Subhost(sld).
+			Route(GET, "/user/:id<int>", 0, func(w http.ResponseWriter, r *http.Request){
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, "hello")
+			}).
+			Route(GET, "/health", 0, func(w http.ResponseWriter, r *http.Request){})
+		
+		r := test_request(t, method, url)
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.Header.Set("User-Agent", "test-agent")
+		h.test_handler().ServeHTTP(httptest.NewRecorder(), r)
+	}
+	
+	t.Run("combined", func(t *testing.T){
+		test_serve(LOG_COMBINED, http.MethodGet, base_url+"/user/7?a=1")
+		re := regexp.MustCompile(`^127\.0\.0\.1 - - \[[^\]]+\] "GET /user/7\?a=1 HTTP/1\.1" 201 5 "-" "test-agent" \d+\.\d{3} "subdomain\." "/user/:id<int>"\n$`)
+		if !re.Match(buf.Bytes()) {
+			t.Fatalf("Unexpected log line: %s", buf.String())
+		}
+	})
+	
+	t.Run("common", func(t *testing.T){
+		test_serve(LOG_COMMON, http.MethodGet, base_url+"/user/7")
+		if !strings.Contains(buf.String(), `"GET /user/7 HTTP/1.1" 201 5 `) || strings.Contains(buf.String(), "test-agent") {
+			t.Fatalf("Unexpected log line: %s", buf.String())
+		}
+	})
+	
+	t.Run("JSON", func(t *testing.T){
+		test_serve(LOG_JSON, http.MethodGet, base_url+"/user/7")
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSON log line: %v: %s", err, buf.String())
+		}
+		want := map[string]any{
+			"client_ip":	"127.0.0.1",
+			"status":		float64(201),
+			"bytes":		float64(5),
+			"subhost":		sld,
+			"route":		"/user/:id<int>",
+		}
+		for k, v := range want {
+			if entry[k] != v {
+				t.Fatalf("Log field %s want [%v] but got [%v]", k, v, entry[k])
+			}
+		}
+	})
+	
+	t.Run("exclude", func(t *testing.T){
+		test_serve(LOG_COMBINED, http.MethodGet, base_url+"/health")
+		if buf.Len() != 0 {
+			t.Fatalf("Excluded path was logged: %s", buf.String())
+		}
+	})
+	
+	t.Run("unmatched", func(t *testing.T){
+		for _, test := range []struct{
+			method	string
+			url		string
+			want	string
+		}{
+			{http.MethodGet, base_url+"/missing", `"GET /missing HTTP/1.1" 404 `},
+			{http.MethodPost, base_url+"/user/7", `"POST /user/7 HTTP/1.1" 405 `},
+			{http.MethodOptions, base_url+"/user/7", `"OPTIONS /user/7 HTTP/1.1" 204 - `},
+			{http.MethodGet, "unknown."+tld+"/", `"GET / HTTP/1.1" 404 `},
+		} {
+			test_serve(LOG_COMMON, test.method, test.url)
+			if !strings.Contains(buf.String(), test.want) {
+				t.Fatalf("Log line want [%s] but got: %s", test.want, buf.String())
+			}
+		}
+	})
+}
\ No newline at end of file
@@ -9,4 +9,12 @@ func Adapt(h http.HandlerFunc, wrappers... Adapter) http.HandlerFunc {
 		h = wrapper(h)
 	}
 	return h
+}
+
+//	Apply adapters so the first adapter is executed first
+func chain(h http.HandlerFunc, adapters []Adapter) http.HandlerFunc {
+	for i := len(adapters)-1; i >= 0; i-- {
+		h = adapters[i](h)
+	}
+	return h
 }
\ No newline at end of file
@@ -0,0 +1,247 @@
+package serv
+
+import (
+	"io"
+	"fmt"
+	"net"
+	"sync"
+	"bufio"
+	"slices"
+	"strings"
+	"net/http"
+	"compress/gzip"
+	"compress/zlib"
+)
+
+const compress_min_size = 1024
+
+var compress_types = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	TYPE_PROBLEM_JSON,
+	"application/xml",
+	"image/svg+xml",
+}
+
+type (
+	Compress_options struct {
+		//	Minimum response size in bytes (default 1024)
+		Min_size	int
+		//	Content types to compress (default text, JavaScript, JSON, XML and SVG)
+		Types		[]string
+		//	Compression level gzip.HuffmanOnly (-2) to gzip.BestCompression (9), default gzip.DefaultCompression if 0
+		//	(gzip.NoCompression can not be selected since 0 is the default, skip the adapter instead)
+		Level		int
+	}
+	
+	compressor struct {
+		opts		Compress_options
+		gzip		sync.Pool
+		deflate		sync.Pool
+	}
+	
+	encoder interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+	
+	//	Response is buffered until the minimum size is reached to decide if it should be compressed
+	compress_writer struct {
+		http.ResponseWriter
+		c			*compressor
+		encoding	string
+		status		int
+		buf			[]byte
+		decided		bool
+		encoder		encoder
+	}
+)
+
+//	Compress responses with gzip or deflate negotiated via Accept-Encoding (panics on invalid compression level)
+func Compress(opts Compress_options) Adapter {
+	if opts.Level < gzip.HuffmanOnly || opts.Level > gzip.BestCompression {
+		panic(fmt.Sprintf("Compression level must be between %d and %d: %d", gzip.HuffmanOnly, gzip.BestCompression, opts.Level))
+	}
+	if opts.Min_size == 0 {
+		opts.Min_size = compress_min_size
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = compress_types
+	}
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	
+	c := &compressor{
+		opts: opts,
+	}
+	c.gzip.New = func() any {
+		w, _ := gzip.NewWriterLevel(nil, opts.Level)
+		return w
+	}
+	c.deflate.New = func() any {
+		w, _ := zlib.NewWriterLevel(nil, opts.Level)
+		return w
+	}
+	
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request){
+			w.Header().Add("Vary", "Accept-Encoding")
+			
+			encoding := compress_encoding(r)
+			if encoding == "" {
+				next(w, r)
+				return
+			}
+			
+			cw := &compress_writer{
+				ResponseWriter:	w,
+				c:				c,
+				encoding:		encoding,
+			}
+			//	Wrapped in Writer so Status(), Sent(), Flush() and Hijack() keep working in the handler
+			next(NewWriter(cw), r)
+			cw.close()
+		}
+	}
+}
+
+//	Negotiate content coding (gzip is preferred)
+func compress_encoding(r *http.Request) string {
+	if accept_encoding(r, "gzip") {
+		return "gzip"
+	}
+	if accept_encoding(r, "deflate") {
+		return "deflate"
+	}
+	return ""
+}
+
+func (c *compressor) pool(encoding string) *sync.Pool {
+	if encoding == "gzip" {
+		return &c.gzip
+	}
+	return &c.deflate
+}
+
+func (cw *compress_writer) WriteHeader(status int){
+	//	Informational responses are sent immediately
+	if cw.decided || status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compress_writer) Write(b []byte) (int, error){
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+	
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.c.opts.Min_size {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+//	Flush buffered and compressed data (for streaming responses)
+func (cw *compress_writer) Flush(){
+	if !cw.decided {
+		cw.decide()
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compress_writer) Hijack() (net.Conn, *bufio.ReadWriter, error){
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Underlying ResponseWriter does not support hijacking")
+}
+
+func (cw *compress_writer) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+//	Decide if response is compressed and write buffered data
+func (cw *compress_writer) decide() error {
+	cw.decided = true
+	if cw.status == 0 {
+		return nil
+	}
+	
+	if len(cw.buf) >= cw.c.opts.Min_size && cw.compressible() {
+		header := cw.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		
+		cw.encoder = cw.c.pool(cw.encoding).Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+	
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (cw *compress_writer) compressible() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" || strings.Contains(header.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+	
+	content_type := header.Get("Content-Type")
+	if content_type == "" {
+		content_type = http.DetectContentType(cw.buf)
+		header.Set("Content-Type", content_type)
+	}
+	media_type, _, _ := strings.Cut(content_type, ";")
+	return slices.Contains(cw.c.opts.Types, strings.ToLower(strings.TrimSpace(media_type)))
+}
+
+//	Write buffered data and finish compressed stream after the handler returns
+func (cw *compress_writer) close(){
+	if !cw.decided {
+		cw.decide()
+	}
+	if cw.encoder != nil {
+		cw.encoder.Close()
+		cw.c.pool(cw.encoding).Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
\ No newline at end of file
@@ -0,0 +1,159 @@
+package serv
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"compress/gzip"
+	"compress/zlib"
+)
+
+func Test_compress(t *testing.T){
+	large := strings.Repeat(`{"name":"value"},`, 100)
+	
+	var (
+		status	int
+		sent	int
+	)
+	
+	h := NewHTTP(tld, "", 0)
+	h.Use(Compress(Compress_options{}))
+	h.Subhost(sld).
+		Route(GET, "/json", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "1700")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, large)
+			status, sent = w.(*Writer).Status(), w.(*Writer).Sent()
+		}).
+		Route(GET, "/small", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{}`)
+		}).
+		Route(GET, "/image", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		}).
+		Route(GET, "/sniff", 0, func(w http.ResponseWriter, r *http.Request){
+			io.WriteString(w, "<html>"+large)
+		}).
+		Route(GET, "/encoded", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		}).
+		Route(GET, "/stream", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, large)
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "end")
+		}).
+		Route(GET, "/hijack", 0, func(w http.ResponseWriter, r *http.Request){
+			if _, _, err := w.(http.Hijacker).Hijack(); err == nil || !strings.Contains(err.Error(), "hijacking") {
+				t.Errorf("Hijack want unsupported error from recorder but got %v", err)
+			}
+		})
+	
+	request := func(path, accept_encoding string) *httptest.ResponseRecorder {
+		r := test_request(t, http.MethodGet, base_url+path)
+		if accept_encoding != "" {
+			r.Header.Set("Accept-Encoding", accept_encoding)
+		}
+		w := httptest.NewRecorder()
+		h.test_handler().ServeHTTP(w, r)
+		return w
+	}
+	
+	t.Run("gzip", func(t *testing.T){
+		w := request("/json", "gzip, deflate")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Status want 201 but got %d", w.Code)
+		}
+		test_header(t, w, "Content-Encoding", "gzip")
+		test_header(t, w, "Content-Length", "")
+		test_header(t, w, "Vary", "Accept-Encoding")
+		
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != large {
+			t.Fatalf("Decompressed body mismatch: %s", b)
+		}
+		if status != http.StatusCreated || sent != len(large) {
+			t.Fatalf("Writer want status 201 and %d bytes but got %d and %d", len(large), status, sent)
+		}
+	})
+	
+	t.Run("deflate", func(t *testing.T){
+		w := request("/json", "deflate, gzip;q=0")
+		test_header(t, w, "Content-Encoding", "deflate")
+		
+		zr, err := zlib.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != large {
+			t.Fatalf("Decompressed body mismatch: %s", b)
+		}
+	})
+	
+	t.Run("sniff", func(t *testing.T){
+		w := request("/sniff", "gzip")
+		test_header(t, w, "Content-Encoding", "gzip")
+		test_header(t, w, "Content-Type", "text/html; charset=utf-8")
+	})
+	
+	t.Run("stream", func(t *testing.T){
+		w := request("/stream", "gzip")
+		if !w.Flushed {
+			t.Fatal("Response want flushed")
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != large+"end" {
+			t.Fatalf("Decompressed body mismatch: %s", b)
+		}
+	})
+	
+	t.Run("uncompressed", func(t *testing.T){
+		for path, accept_encoding := range map[string]string{
+			"/json":	"",
+			"/small":	"gzip",
+			"/image":	"gzip",
+			"/encoded":	"gzip",
+		} {
+			w := request(path, accept_encoding)
+			if got := w.Header().Get("Content-Encoding"); got == "gzip" {
+				t.Fatalf("%s want uncompressed", path)
+			}
+			if w.Body.Len() < 2 {
+				t.Fatalf("%s body missing", path)
+			}
+		}
+	})
+	
+	t.Run("hijack", func(t *testing.T){
+		request("/hijack", "gzip")
+	})
+	
+	t.Run("level", func(t *testing.T){
+		for _, level := range []int{gzip.HuffmanOnly, gzip.BestSpeed, gzip.BestCompression} {
+			Compress(Compress_options{Level: level})
+		}
+		for _, level := range []int{gzip.HuffmanOnly - 1, gzip.BestCompression + 1} {
+			func(){
+				defer func(){
+					if recover() == nil {
+						t.Fatalf("Level %d want panic", level)
+					}
+				}()
+				Compress(Compress_options{Level: level})
+			}()
+		}
+	})
+}
\ No newline at end of file
@@ -0,0 +1,188 @@
+package serv
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"net/http"
+)
+
+type CORS struct {
+	//	Allowed origins: exact "https://app.domain.com", pattern "https://*.domain.com" or any "*"
+	Origins			[]string
+	//	Allowed methods (default the methods registered on the route)
+	Methods			[]Method
+	//	Allowed request headers (default the headers requested in preflight)
+	Headers			[]string
+	//	Response headers exposed to the browser
+	Expose_headers	[]string
+	//	Allow cookies and authorization (can not be combined with any origin "*")
+	Credentials		bool
+	//	Seconds the preflight response can be cached
+	Max_age			int
+}
+
+//	Apply CORS policy to all routes on subhost (preflight requests are answered automatically)
+func (s *Subhost) CORS(c CORS) *Subhost {
+	if err := validate_cors(c); err != nil {
+		s.add_error(err)
+		return s
+	}
+	s.cors = &c
+	return s
+}
+
+//	Apply CORS policy to all routes in group and nested groups (overrides subhost policy)
+func (g *Group) CORS(c CORS) *Group {
+	if err := validate_cors(c); err != nil {
+		g.subhost.add_error(err)
+		return g
+	}
+	g.cors = &c
+	return g
+}
+
+func validate_cors(c CORS) error {
+	if len(c.Origins) == 0 {
+		return fmt.Errorf("CORS requires allowed origins")
+	}
+	for _, origin := range c.Origins {
+		if origin == "*" && c.Credentials {
+			return fmt.Errorf("CORS credentials can not be allowed for any origin")
+		}
+		if origin != "*" && strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("CORS origin pattern can only have one wildcard: %s", origin)
+		}
+	}
+	return nil
+}
+
+//	Get CORS policy of nearest group or subhost
+func (rh *route_handler) cors(s *Subhost) *CORS {
+	for g := rh.group; g != nil; g = g.parent {
+		if g.cors != nil {
+			return g.cors
+		}
+	}
+	return s.cors
+}
+
+//	Answer CORS preflight request with the methods registered on the route
+func (s *Subhost) preflight(route *route, w http.ResponseWriter, r *http.Request) bool {
+	request_method := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != http.MethodOptions || request_method == "" || r.Header.Get("Origin") == "" {
+		return false
+	}
+	
+	handler, ok := route.methods[request_method]
+	if !ok {
+		handler, ok = route.methods[string(ALL)]
+	}
+	//	Resolve HEAD with GET route like match_method
+	if !ok && request_method == http.MethodHead {
+		handler, ok = route.methods[http.MethodGet]
+	}
+	//	Requests without a CORS policy are handled like any other OPTIONS request
+	var c *CORS
+	if ok {
+		c = handler.cors(s)
+	} else {
+		for _, handler := range route.methods {
+			if c = handler.cors(s); c != nil {
+				break
+			}
+		}
+	}
+	if c == nil {
+		return false
+	}
+	
+	header := w.Header()
+	header.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	
+	//	Disallowed preflight is answered without CORS headers
+	methods := c.allow_methods(route, request_method)
+	if ok && !handler.blind && slices.Contains(methods, request_method) && c.allow_origin(w, r) {
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(c.Headers) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(c.Headers, ", "))
+		} else if request_headers := r.Header.Get("Access-Control-Request-Headers"); request_headers != "" {
+			header.Set("Access-Control-Allow-Headers", request_headers)
+		}
+		if c.Max_age > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(c.Max_age))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+//	Apply CORS headers to response if origin is allowed
+func (c *CORS) allow_origin(w http.ResponseWriter, r *http.Request) bool {
+	if c == nil {
+		return false
+	}
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.match_origin(origin) {
+		return false
+	}
+	
+	if slices.Contains(c.Origins, "*") {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.Credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(c.Expose_headers) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(c.Expose_headers, ", "))
+	}
+	return true
+}
+
+func (c *CORS) match_origin(origin string) bool {
+	for _, allowed := range c.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		prefix, suffix, found := strings.Cut(allowed, "*")
+		if found && len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+//	Get allowed methods from policy or from the methods registered on the route (blind routes are skipped and HEAD is allowed with GET)
+func (c *CORS) allow_methods(route *route, request_method string) []string {
+	var methods []string
+	if len(c.Methods) > 0 {
+		for _, method := range c.Methods {
+			methods = append(methods, string(method))
+		}
+		return methods
+	}
+	for method, handler := range route.methods {
+		if handler.blind {
+			continue
+		}
+		if method == string(ALL) {
+			method = request_method
+		}
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	slices.Sort(methods)
+	return methods
+}
\ No newline at end of file
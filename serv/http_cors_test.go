@@ -0,0 +1,131 @@
+package serv
+
+import (
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_cors(t *testing.T){
+	handler := func(w http.ResponseWriter, r *http.Request){}
+	
+	h := NewHTTP(tld, "", 0)
+	s := h.Subhost(sld).
+		CORS(CORS{
+			Origins:		[]string{"https://app.domain.com", "https://*.partner.com"},
+			Credentials:	true,
+			Max_age:		600,
+		}).
+		Route(GET, "/user", 0, handler).
+		Route(POST, "/user", 0, handler).
+		Route_blind(DELETE, "/user").
+		Route(GET, "/private", 0, handler)
+	s.Group("/public").
+		CORS(CORS{
+			Origins:		[]string{"*"},
+			Headers:		[]string{"Content-Type"},
+			Expose_headers:	[]string{"X-Total"},
+		}).
+		Route(ALL, "/list", 0, handler)
+	
+	request := func(method, url string, header map[string]string) *httptest.ResponseRecorder {
+		r := test_request(t, method, url)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.test_handler().ServeHTTP(w, r)
+		return w
+	}
+	
+	t.Run("preflight", func(t *testing.T){
+		w := request(http.MethodOptions, base_url+"/user", map[string]string{
+			"Origin":							"https://app.domain.com",
+			"Access-Control-Request-Method":	"POST",
+			"Access-Control-Request-Headers":	"Content-Type, X-CSRF",
+		})
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Preflight want HTTP 204 but got %d", w.Code)
+		}
+		test_header(t, w, "Access-Control-Allow-Origin", "https://app.domain.com")
+		test_header(t, w, "Access-Control-Allow-Methods", "GET, HEAD, POST")
+		test_header(t, w, "Access-Control-Allow-Headers", "Content-Type, X-CSRF")
+		test_header(t, w, "Access-Control-Allow-Credentials", "true")
+		test_header(t, w, "Access-Control-Max-Age", "600")
+	})
+	
+	t.Run("preflight HEAD", func(t *testing.T){
+		w := request(http.MethodOptions, base_url+"/user", map[string]string{
+			"Origin":							"https://app.domain.com",
+			"Access-Control-Request-Method":	"HEAD",
+		})
+		test_header(t, w, "Access-Control-Allow-Origin", "https://app.domain.com")
+		test_header(t, w, "Access-Control-Allow-Methods", "GET, HEAD, POST")
+	})
+	
+	t.Run("preflight pattern origin", func(t *testing.T){
+		w := request(http.MethodOptions, base_url+"/user", map[string]string{
+			"Origin":							"https://shop.partner.com",
+			"Access-Control-Request-Method":	"GET",
+		})
+		test_header(t, w, "Access-Control-Allow-Origin", "https://shop.partner.com")
+	})
+	
+	t.Run("preflight disallowed", func(t *testing.T){
+		for name, header := range map[string]map[string]string{
+			"origin":	{"Origin": "https://evil.com", "Access-Control-Request-Method": "GET"},
+			"pattern":	{"Origin": "https://partner.com", "Access-Control-Request-Method": "GET"},
+			"method":	{"Origin": "https://app.domain.com", "Access-Control-Request-Method": "DELETE"},
+		} {
+			w := request(http.MethodOptions, base_url+"/user", header)
+			if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Fatalf("Preflight disallowed %s want HTTP 204 without CORS headers but got %d %v", name, w.Code, w.Header())
+			}
+		}
+	})
+	
+	t.Run("preflight group", func(t *testing.T){
+		w := request(http.MethodOptions, base_url+"/public/list", map[string]string{
+			"Origin":							"https://any.com",
+			"Access-Control-Request-Method":	"PATCH",
+		})
+		test_header(t, w, "Access-Control-Allow-Origin", "*")
+		test_header(t, w, "Access-Control-Allow-Methods", "PATCH")
+		test_header(t, w, "Access-Control-Allow-Headers", "Content-Type")
+		test_header(t, w, "Access-Control-Allow-Credentials", "")
+	})
+	
+	t.Run("request", func(t *testing.T){
+		w := request(http.MethodGet, base_url+"/public/list", map[string]string{"Origin": "https://any.com"})
+		test_header(t, w, "Access-Control-Allow-Origin", "*")
+		test_header(t, w, "Access-Control-Expose-Headers", "X-Total")
+		test_header(t, w, "Vary", "Origin")
+		
+		w = request(http.MethodGet, base_url+"/user", map[string]string{"Origin": "https://evil.com"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request want HTTP 200 but got %d", w.Code)
+		}
+		test_header(t, w, "Access-Control-Allow-Origin", "")
+	})
+	
+	t.Run("options without preflight", func(t *testing.T){
+		w := request(http.MethodOptions, base_url+"/user", nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("OPTIONS want HTTP 204 but got %d", w.Code)
+		}
+		test_header(t, w, "Allow", "GET, HEAD, OPTIONS, POST")
+		test_header(t, w, "Access-Control-Allow-Origin", "")
+	})
+	
+	t.Run("credentials with any origin", func(t *testing.T){
+		h := NewHTTP(tld, "", 0)
+		h.Subhost(sld).CORS(CORS{
+			Origins:		[]string{"*"},
+			Credentials:	true,
+		})
+		if err := h.Validate(); err == nil || !strings.Contains(err.Error(), "CORS credentials can not be allowed for any origin") {
+			t.Fatalf("Validate want credentials error but got %v", err)
+		}
+	})
+}
\ No newline at end of file
@@ -0,0 +1,95 @@
+package serv
+
+import (
+	"log"
+	"net/http"
+	"encoding/json/v2"
+	"github.com/go-errors/errors"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+const TYPE_PROBLEM_JSON = "application/problem+json"
+
+type (
+	//	Error response handler receives the recovered panic (HTTP 500) or error (if any)
+	Error_handler func(w http.ResponseWriter, r *http.Request, status int, err any)
+	
+	//	RFC 9457 problem details
+	Problem struct {
+		Type		string	`json:"type"`
+		Title		string	`json:"title"`
+		Status		int		`json:"status"`
+		Detail		string	`json:"detail,omitempty"`
+		Instance	string	`json:"instance,omitempty"`
+	}
+)
+
+//	Apply error response handler for HTTP 404, 405, 408 and 500
+func (s *Subhost) Errors(handler Error_handler) *Subhost {
+	s.error_handler = handler
+	return s
+}
+
+//	Apply JSON error responses (application/problem+json)
+func (s *Subhost) Errors_JSON() *Subhost {
+	return s.Errors(Error_JSON)
+}
+
+//	Plain text error response
+func Error_text(w http.ResponseWriter, r *http.Request, status int, err any){
+	http.Error(w, http.StatusText(status), status)
+}
+
+//	JSON error response (application/problem+json)
+func Error_JSON(w http.ResponseWriter, r *http.Request, status int, err any){
+	Write_problem(w, Problem{
+		Type:		"about:blank",
+		Title:		http.StatusText(status),
+		Status:		status,
+		Instance:	r.URL.Path,
+	})
+}
+
+//	Write problem details (application/problem+json). Invalid status codes are written as HTTP 500
+func Write_problem(w http.ResponseWriter, p Problem){
+	if p.Status < 100 || p.Status > 999 {
+		p.Status	= http.StatusInternalServerError
+		p.Title		= http.StatusText(p.Status)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", TYPE_PROBLEM_JSON)
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(b)
+}
+
+//	Write error response with the error handler of the subhost serving the request (e.g. from adapters)
+func Error(w http.ResponseWriter, r *http.Request, status int, err any){
+	s, _ := r.Context().Value(ctx_errors).(*Subhost)
+	s.error(w, r, status, err)
+}
+
+//	Write error response with subhost error handler
+func (s *Subhost) error(w http.ResponseWriter, r *http.Request, status int, err any){
+	if s == nil || s.error_handler == nil {
+		Error_text(w, r, status, err)
+		return
+	}
+	s.error_handler(w, r, status, err)
+}
+
+//	Recover from panic inside route handler with subhost error handler
+func (s *Subhost) recover_panic(w http.ResponseWriter, r *http.Request){
+	if err := recover(); err != nil {
+		if rw, ok := w.(*Writer); ok && !rw.Sent_header() {
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+		log.Printf("Request ID %s: %s", req.Request_ID(r), errors.Wrap(err, 2).ErrorStack())
+	}
+}
\ No newline at end of file
@@ -0,0 +1,102 @@
+package serv
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Group struct {
+	subhost		*Subhost
+	parent		*Group
+	prefix		string
+	timeout		int
+	adapters	[]Adapter
+	cors		*CORS
+}
+
+//	Apply group of routes with path prefix
+func (s *Subhost) Group(prefix string) *Group {
+	return newGroup(s, nil, s.group_prefix(prefix), 0)
+}
+
+//	Apply nested group of routes with path prefix
+func (g *Group) Group(prefix string) *Group {
+	return newGroup(g.subhost, g, g.prefix+g.subhost.group_prefix(prefix), g.timeout)
+}
+
+func newGroup(s *Subhost, parent *Group, prefix string, timeout int) *Group {
+	return &Group{
+		subhost:	s,
+		parent:		parent,
+		prefix:		prefix,
+		timeout:	timeout,
+	}
+}
+
+//	Apply middleware to all routes in group and nested groups (executed after subhost and parent group middleware)
+func (g *Group) Use(adapters ...Adapter) *Group {
+	g.adapters = append(g.adapters, adapters...)
+	return g
+}
+
+//	Apply default timeout to routes applied afterwards in group and nested groups with timeout 0
+func (g *Group) Timeout(timeout int) *Group {
+	g.timeout = timeout_min(timeout)
+	return g
+}
+
+//	Apply route pattern exact with optional route middleware
+func (g *Group) Route_exact(method Method, pattern string, timeout int, handler http.HandlerFunc, adapters ...Adapter) *Group {
+	if pattern, ok := g.pattern(pattern); ok {
+		g.subhost.route(method, pattern, g.route_timeout(timeout), handler, adapters, g, true, false)
+	}
+	return g
+}
+
+//	Apply route pattern with optional route middleware
+func (g *Group) Route(method Method, pattern string, timeout int, handler http.HandlerFunc, adapters ...Adapter) *Group {
+	if pattern, ok := g.pattern(pattern); ok {
+		g.subhost.route(method, pattern, g.route_timeout(timeout), handler, adapters, g, false, false)
+	}
+	return g
+}
+
+//	Apply blind route pattern (HTTP 404)
+func (g *Group) Route_blind(method Method, pattern string) *Group {
+	if pattern, ok := g.pattern(pattern); ok {
+		var handler http.HandlerFunc
+		g.subhost.route(method, pattern, 0, handler, nil, g, false, true)
+	}
+	return g
+}
+
+func (g *Group) pattern(pattern string) (string, bool){
+	if err := validate_pattern(pattern); err != nil {
+		g.subhost.add_error(err)
+		return "", false
+	}
+	if pattern == "/" {
+		return g.prefix, true
+	}
+	return g.prefix+pattern, true
+}
+
+func (g *Group) route_timeout(timeout int) int {
+	if timeout == 0 {
+		return g.timeout
+	}
+	return timeout
+}
+
+//	Validate group prefix (invalid prefixes are collected as errors)
+func (s *Subhost) group_prefix(prefix string) string {
+	if err := validate_pattern(prefix); err != nil {
+		s.add_error(err)
+		return prefix
+	}
+	prefix = strip_trailing_slash(prefix)
+	if prefix == "/" {
+		s.add_error(fmt.Errorf("Group prefix can not be the root path"))
+	}
+	return prefix
+}
\ No newline at end of file
@@ -0,0 +1,86 @@
+package serv
+
+import (
+	"net"
+	"fmt"
+	"slices"
+	"strings"
+	"net/http"
+)
+
+//	Apply additional TLD served by the same subhosts (e.g. staging and production domain)
+func (h *HTTP) Add_TLD(tld string) *HTTP {
+	tld = strings.ToLower(tld)
+	if tld == "" || tld[0] == '.' {
+		h.add_error(fmt.Errorf("Invalid TLD: %s", tld))
+		return h
+	}
+	if slices.Contains(h.tlds, tld) {
+		h.add_error(fmt.Errorf("TLD already exists: %s", tld))
+		return h
+	}
+	h.tlds = append(h.tlds, tld)
+	
+	//	Longest TLD is matched first
+	slices.SortStableFunc(h.tlds, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	return h
+}
+
+//	Get primary TLD
+func (h *HTTP) TLD() string {
+	return h.tld
+}
+
+//	Apply subhost for the apex domain (TLD without subdomain)
+func (h *HTTP) Apex() *Subhost {
+	return h.subhost("", "")
+}
+
+//	Get label captured by a wildcard subhost: "*." matches "customer.domain.com" -> "customer"
+func Subhost_label(r *http.Request) string {
+	label, _ := r.Context().Value(ctx_label).(string)
+	return label
+}
+
+//	Get subhost matched by the request: "api.", "*." (wildcard) or "" (apex)
+func Subhost_name(r *http.Request) string {
+	sld, _ := r.Context().Value(ctx_subhost).(string)
+	return sld
+}
+
+//	Resolve subhost from Host header or SNI server name (wildcard subhosts match a single label)
+func (h *HTTP) resolve_subhost(host string) (*Subhost, string, error){
+	host = normalize_host(host)
+	
+	for _, tld := range h.tlds {
+		var sld string
+		if host != tld {
+			if !strings.HasSuffix(host, "."+tld) {
+				continue
+			}
+			sld = host[:len(host)-len(tld)]
+		}
+		
+		if s, ok := h.subhosts[sld]; ok {
+			return s, "", nil
+		}
+		if sld != "" {
+			label, parent, _ := strings.Cut(sld, ".")
+			if s, ok := h.subhosts["*."+parent]; ok && label != "" {
+				return s, label, nil
+			}
+		}
+		return nil, "", fmt.Errorf("Unsupported subhost (SLD %s): %s", sld, host)
+	}
+	return nil, "", fmt.Errorf("Unsupported host (TLD %s): %s", strings.Join(h.tlds, ", "), host)
+}
+
+//	Strip port and trailing dot from host
+func normalize_host(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
\ No newline at end of file
@@ -0,0 +1,214 @@
+package serv
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"errors"
+	"context"
+	"strings"
+	"net/http"
+	"encoding/json/v2"
+	"encoding/json/jsontext"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+const (
+	TYPE_JSON		= "application/json"
+	
+	json_limit_kb	= 1024
+)
+
+type (
+	JSON_options struct {
+		//	Request body limit in KB (default 1024)
+		Limit_kb	int
+		//	Response status on success (default 200)
+		Status		int
+	}
+	
+	//	Error with HTTP status returned from typed handlers (detail is sent to the client and an invalid status is sent as HTTP 500)
+	Status_error struct {
+		Status	int
+		Detail	string
+		Err		error
+	}
+	
+	//	Request input validated after decoding (HTTP 422 on error)
+	Validator interface {
+		Validate() error
+	}
+)
+
+//	Create error with HTTP status and detail sent to the client
+func NewStatus_error(status int, detail string) *Status_error {
+	return &Status_error{
+		Status:	status,
+		Detail:	detail,
+	}
+}
+
+func (e *Status_error) Error() string {
+	if e.Detail == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Status_error) Unwrap() error {
+	return e.Err
+}
+
+/*
+	Typed JSON handler: the request body is decoded strictly (unknown members are rejected) into In and the returned Out is encoded as response
+	
+	Requests without body (e.g. GET) leave In as zero value. Errors are sent as problem details (application/problem+json):
+	415 Content-Type is not JSON, 413 body exceeds limit, 400 malformed JSON, 422 In.Validate() failed, *Status_error with its own status and 500 on any other error
+*/
+func JSON[In, Out any](handler func(ctx context.Context, in In) (Out, error), opts ...JSON_options) http.HandlerFunc {
+	var o JSON_options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Limit_kb == 0 {
+		o.Limit_kb = json_limit_kb
+	}
+	if o.Status == 0 {
+		o.Status = http.StatusOK
+	}
+	return func(w http.ResponseWriter, r *http.Request){
+		var in In
+		if err := decode_JSON(w, r, o.Limit_kb, &in); err != nil {
+			write_JSON_error(w, r, err)
+			return
+		}
+		if err := validate_JSON(&in); err != nil {
+			write_JSON_error(w, r, err)
+			return
+		}
+		
+		out, err := handler(r.Context(), in)
+		if err != nil {
+			write_JSON_error(w, r, err)
+			return
+		}
+		
+		if o.Status == http.StatusNoContent {
+			w.WriteHeader(o.Status)
+			return
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			write_JSON_error(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", TYPE_JSON)
+		w.WriteHeader(o.Status)
+		w.Write(append(b, '\n'))
+	}
+}
+
+//	Get named route parameter from the request context (e.g. inside typed JSON handlers)
+func Context_param(ctx context.Context, name string) string {
+	names, _ := ctx.Value(ctx_param).([]string)
+	slugs, _ := ctx.Value(ctx_slug).([]string)
+	for i, param := range names {
+		if param == name && i < len(slugs) {
+			return slugs[i]
+		}
+	}
+	return ""
+}
+
+func decode_JSON(w http.ResponseWriter, r *http.Request, limit_kb int, v any) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	
+	media, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (media != TYPE_JSON && !strings.HasSuffix(media, "+json")) {
+		return NewStatus_error(http.StatusUnsupportedMediaType, "Content-Type must be "+TYPE_JSON)
+	}
+	
+	b, err := req.Post_limit_read(w, r, limit_kb)
+	if err != nil {
+		var max_bytes *http.MaxBytesError
+		if errors.As(err, &max_bytes) {
+			return NewStatus_error(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d KB", limit_kb))
+		}
+		return &Status_error{
+			Status:	http.StatusBadRequest,
+			Detail:	"Unable to read request body",
+			Err:	err,
+		}
+	}
+	
+	if err := json.Unmarshal(b, v, json.RejectUnknownMembers(true)); err != nil {
+		return &Status_error{
+			Status:	http.StatusBadRequest,
+			Detail:	json_error_detail(err),
+			Err:	err,
+		}
+	}
+	return nil
+}
+
+//	Validate decoded input with value or pointer receiver
+func validate_JSON[In any](in *In) error {
+	v, ok := any(*in).(Validator)
+	if !ok {
+		if v, ok = any(in).(Validator); !ok {
+			return nil
+		}
+	}
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+	var serr *Status_error
+	if errors.As(err, &serr) {
+		return err
+	}
+	return &Status_error{
+		Status:	http.StatusUnprocessableEntity,
+		Detail:	err.Error(),
+		Err:	err,
+	}
+}
+
+//	Describe decoding error without exposing Go types
+func json_error_detail(err error) string {
+	var serr *json.SemanticError
+	if errors.As(err, &serr) {
+		if errors.Is(serr.Err, json.ErrUnknownName) {
+			return "Unknown member "+string(serr.JSONPointer)
+		}
+		if serr.JSONPointer != "" {
+			return "Invalid value at "+string(serr.JSONPointer)
+		}
+		return "Invalid value"
+	}
+	var syntax_err *jsontext.SyntacticError
+	if errors.As(err, &syntax_err) {
+		return fmt.Sprintf("Malformed JSON at byte offset %d", syntax_err.ByteOffset)
+	}
+	return "Malformed JSON"
+}
+
+//	Write problem details for typed handler error (details of unknown errors are only logged)
+func write_JSON_error(w http.ResponseWriter, r *http.Request, err error){
+	p := Problem{
+		Type:		"about:blank",
+		Instance:	r.URL.Path,
+	}
+	var serr *Status_error
+	if errors.As(err, &serr) {
+		p.Status = serr.Status
+		p.Detail = serr.Detail
+	} else {
+		p.Status = http.StatusInternalServerError
+		log.Printf("Request ID %s: %v", req.Request_ID(r), err)
+	}
+	p.Title = http.StatusText(p.Status)
+	Write_problem(w, p)
+}
\ No newline at end of file
@@ -0,0 +1,133 @@
+package serv
+
+import (
+	"errors"
+	"context"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"encoding/json/v2"
+)
+
+type (
+	test_user_in struct {
+		Name	string	`json:"name"`
+		Age		int		`json:"age"`
+	}
+	
+	test_user_out struct {
+		ID		string	`json:"id"`
+		Name	string	`json:"name"`
+	}
+)
+
+func (in test_user_in) Validate() error {
+	if in.Name == "" {
+		return errors.New("Name is required")
+	}
+	return nil
+}
+
+func Test_JSON(t *testing.T){
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Route(POST, "/user/:id<int>", 0, JSON(func(ctx context.Context, in test_user_in) (test_user_out, error){
+			switch in.Name {
+			case "taken":
+				return test_user_out{}, NewStatus_error(http.StatusConflict, "Name is taken")
+			case "fail":
+				return test_user_out{}, errors.New("Database is down")
+			case "no status":
+				return test_user_out{}, &Status_error{Detail: "Status is missing"}
+			}
+			return test_user_out{
+				ID:		Context_param(ctx, "id"),
+				Name:	in.Name,
+			}, nil
+		}, JSON_options{
+			Limit_kb:	1,
+			Status:		http.StatusCreated,
+		})).
+		Route(GET, "/ping", 0, JSON(func(ctx context.Context, in struct{}) (map[string]bool, error){
+			return map[string]bool{"pong": true}, nil
+		}))
+	
+	request := func(method, path, content_type, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, "//"+base_url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		if body == "" {
+			r.Body = http.NoBody
+		}
+		r.Header.Set("Content-Type", content_type)
+		w := httptest.NewRecorder()
+		h.test_handler().ServeHTTP(w, r)
+		return w
+	}
+	
+	t.Run("success", func(t *testing.T){
+		w := request(http.MethodPost, "/user/7", "application/json; charset=utf-8", `{"name":"john","age":30}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Status want 201 but got %d: %s", w.Code, w.Body.String())
+		}
+		test_header(t, w, "Content-Type", TYPE_JSON)
+		if body := w.Body.String(); body != `{"id":"7","name":"john"}`+"\n" {
+			t.Fatalf("Body mismatch: %s", body)
+		}
+		
+		w = request(http.MethodGet, "/ping", "", "")
+		if w.Code != http.StatusOK || w.Body.String() != `{"pong":true}`+"\n" {
+			t.Fatalf("Want HTTP 200 pong but got %d: %s", w.Code, w.Body.String())
+		}
+	})
+	
+	t.Run("errors", func(t *testing.T){
+		for _, test := range []struct{
+			content_type	string
+			body			string
+			status			int
+			detail			string
+		}{
+			{"text/plain", `{"name":"john"}`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+			{TYPE_JSON, `{"name":"`+strings.Repeat("a", 1024)+`"}`, http.StatusRequestEntityTooLarge, "Request body exceeds 1 KB"},
+			{TYPE_JSON, `{"name":"john","admin":true}`, http.StatusBadRequest, "Unknown member /admin"},
+			{TYPE_JSON, `{"name":"john","age":"30"}`, http.StatusBadRequest, "Invalid value at /age"},
+			{TYPE_JSON, `{"name":`, http.StatusBadRequest, "Malformed JSON at byte offset 8"},
+			{TYPE_JSON, `{"age":30}`, http.StatusUnprocessableEntity, "Name is required"},
+			{"application/vnd.api+json", `{"name":"taken"}`, http.StatusConflict, "Name is taken"},
+			{TYPE_JSON, `{"name":"fail"}`, http.StatusInternalServerError, ""},
+			{TYPE_JSON, `{"name":"no status"}`, http.StatusInternalServerError, "Status is missing"},
+		} {
+			w := request(http.MethodPost, "/user/7", test.content_type, test.body)
+			if w.Code != test.status {
+				t.Fatalf("Body %.40s: status want %d but got %d: %s", test.body, test.status, w.Code, w.Body.String())
+			}
+			test_header(t, w, "Content-Type", TYPE_PROBLEM_JSON)
+			
+			var p Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("Unable to decode problem: %s", err)
+			}
+			want := Problem{
+				Type:		"about:blank",
+				Title:		http.StatusText(test.status),
+				Status:		test.status,
+				Detail:		test.detail,
+				Instance:	"/user/7",
+			}
+			if p != want {
+				t.Fatalf("Problem want %+v but got %+v", want, p)
+			}
+		}
+	})
+	
+	t.Run("invalid problem status", func(t *testing.T){
+		w := httptest.NewRecorder()
+		Write_problem(w, Problem{Type: "about:blank"})
+		if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"status":500`) {
+			t.Fatalf("Problem want HTTP 500 but got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
\ No newline at end of file
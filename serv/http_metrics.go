@@ -0,0 +1,188 @@
+package serv
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"slices"
+	"strconv"
+	"strings"
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	default_buckets	= []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	label_escaper	= strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+type (
+	//	Metrics registry with route patterns as labels to keep cardinality bounded
+	Metrics struct {
+		lock		sync.Mutex
+		buckets		[]float64
+		requests	map[metrics_status]uint64
+		durations	map[metrics_route]*histogram
+		timeouts	map[metrics_route]uint64
+		in_flight	atomic.Int64
+	}
+	
+	metrics_route struct {
+		subhost		string
+		route		string
+	}
+	
+	metrics_status struct {
+		metrics_route
+		status		int
+	}
+	
+	histogram struct {
+		counts		[]uint64
+		sum			float64
+		count		uint64
+	}
+)
+
+//	Create metrics registry with latency histogram buckets in seconds (default buckets if none)
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = default_buckets
+	}
+	buckets = append([]float64{}, buckets...)
+	slices.Sort(buckets)
+	
+	return &Metrics{
+		buckets:	buckets,
+		requests:	map[metrics_status]uint64{},
+		durations:	map[metrics_route]*histogram{},
+		timeouts:	map[metrics_route]uint64{},
+	}
+}
+
+//	Record metrics on all subhosts
+func (h *HTTP) Metrics(m *Metrics) *HTTP {
+	h.metrics = m
+	return h
+}
+
+//	Expose metrics in Prometheus text format
+func (m *Metrics) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request){
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(m.text())
+	}
+}
+
+//	Start observing request and return func to record it when served (the route pattern is resolved while serving)
+func (m *Metrics) observe(w *Writer, subhost string, pattern *string) func(){
+	start := time.Now()
+	m.in_flight.Add(1)
+	
+	return func(){
+		defer m.in_flight.Add(-1)
+		
+		duration	:= time.Since(start).Seconds()
+		route		:= metrics_route{subhost, *pattern}
+		
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		
+		m.requests[metrics_status{route, w.Status()}]++
+		
+		hist, ok := m.durations[route]
+		if !ok {
+			hist = &histogram{
+				counts: make([]uint64, len(m.buckets)),
+			}
+			m.durations[route] = hist
+		}
+		for i, bucket := range m.buckets {
+			if duration <= bucket {
+				hist.counts[i]++
+			}
+		}
+		hist.sum += duration
+		hist.count++
+	}
+}
+
+//	Record route timeout (HTTP 408)
+func (m *Metrics) timeout(subhost, pattern string){
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.timeouts[metrics_route{subhost, pattern}]++
+}
+
+func (m *Metrics) text() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	
+	var b strings.Builder
+	
+	metrics_header(&b, "http_requests_total", "counter", "Total number of HTTP requests by subhost, route pattern and status.")
+	for _, key := range sorted_keys(m.requests, func(a, b metrics_status) int {
+		if a.metrics_route != b.metrics_route {
+			return a.metrics_route.compare(b.metrics_route)
+		}
+		return a.status - b.status
+	}) {
+		fmt.Fprintf(&b, "http_requests_total{%s,status=\"%d\"} %d\n", key.labels(), key.status, m.requests[key])
+	}
+	
+	metrics_header(&b, "http_request_duration_seconds", "histogram", "HTTP request latency by subhost and route pattern.")
+	for _, key := range sorted_keys(m.durations, metrics_route.compare) {
+		hist := m.durations[key]
+		for i, bucket := range m.buckets {
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", key.labels(), format_float(bucket), hist.counts[i])
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), hist.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %s\n", key.labels(), format_float(hist.sum))
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", key.labels(), hist.count)
+	}
+	
+	metrics_header(&b, "http_request_timeouts_total", "counter", "Total number of HTTP requests reaching the route timeout (HTTP 408).")
+	for _, key := range sorted_keys(m.timeouts, metrics_route.compare) {
+		fmt.Fprintf(&b, "http_request_timeouts_total{%s} %d\n", key.labels(), m.timeouts[key])
+	}
+	
+	metrics_header(&b, "http_requests_in_flight", "gauge", "Number of HTTP requests currently being served.")
+	fmt.Fprintf(&b, "http_requests_in_flight %d\n", m.in_flight.Load())
+	
+	return []byte(b.String())
+}
+
+func (k metrics_route) labels() string {
+	return "subhost=\""+escape_label(k.subhost)+"\",route=\""+escape_label(k.route)+"\""
+}
+
+func (k metrics_route) compare(b metrics_route) int {
+	if k.subhost != b.subhost {
+		return strings.Compare(k.subhost, b.subhost)
+	}
+	return strings.Compare(k.route, b.route)
+}
+
+func metrics_header(b *strings.Builder, name, metric_type, help string){
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metric_type)
+}
+
+func sorted_keys[K comparable, V any](m map[K]V, compare func(a, b K) int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, compare)
+	return keys
+}
+
+func escape_label(s string) string {
+	return label_escaper.Replace(s)
+}
+
+func format_float(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
\ No newline at end of file
@@ -0,0 +1,59 @@
+package serv
+
+import (
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_metrics(t *testing.T){
+	m := NewMetrics(0.5, 1)
+	
+	h := NewHTTP(tld, "", 0).Metrics(m)
+	h.Subhost(sld).
+		Route(GET, "/user/:id<int>", 0, func(w http.ResponseWriter, r *http.Request){
+			if m.in_flight.Load() != 1 {
+				t.Errorf("In flight want 1 but got %d", m.in_flight.Load())
+			}
+		}).
+		Route(GET, "/panic", 0, func(w http.ResponseWriter, r *http.Request){
+			panic("test panic")
+		}).
+		Route(GET, "/slow", 1, func(w http.ResponseWriter, r *http.Request){
+			<-r.Context().Done()
+		}).
+		Route(GET, "/metrics", 0, m.Handler())
+	
+	handler := h.test_handler()
+	for _, path := range []string{"/user/1", "/user/2", "/user/abc", "/panic", "/slow"} {
+		handler.ServeHTTP(httptest.NewRecorder(), test_request(t, http.MethodGet, base_url+path))
+	}
+	
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, test_request(t, http.MethodGet, base_url+"/metrics"))
+	body := w.Body.String()
+	
+	for _, want := range []string{
+		`http_requests_total{subhost="subdomain.",route="/user/:id<int>",status="200"} 2`,
+		`http_requests_total{subhost="subdomain.",route="",status="404"} 1`,
+		`http_requests_total{subhost="subdomain.",route="/panic",status="500"} 1`,
+		`http_requests_total{subhost="subdomain.",route="/slow",status="408"} 1`,
+		`http_request_duration_seconds_bucket{subhost="subdomain.",route="/user/:id<int>",le="0.5"} 2`,
+		`http_request_duration_seconds_bucket{subhost="subdomain.",route="/user/:id<int>",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{subhost="subdomain.",route="/slow"} 1`,
+		`http_request_timeouts_total{subhost="subdomain.",route="/slow"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		"http_requests_in_flight 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("Metrics missing %s\n%s", want, body)
+		}
+	}
+}
+
+func Test_metrics_escape_label(t *testing.T){
+	if got, want := escape_label("a\"b\\c\nd"), `a\"b\\c\nd`; got != want {
+		t.Fatalf("Escaped label want %s but got %s", want, got)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,100 @@
+package serv
+
+import (
+	"fmt"
+	"time"
+	"context"
+	"net/http"
+)
+
+const (
+	http2_min_frame_size	= 16 << 10
+	http2_max_frame_size	= 16 << 20
+)
+
+type (
+	Options struct {
+		Read_timeout			time.Duration
+		Read_header_timeout		time.Duration
+		Write_timeout			time.Duration
+		Idle_timeout			time.Duration
+		Max_header_bytes		int
+		//	Deadline for graceful shutdown and shutdown hooks (no deadline if 0)
+		Shutdown_timeout		time.Duration
+		//	Accept cleartext HTTP/2 with prior knowledge (h2c) next to HTTP/1.1, e.g. behind a load balancer
+		H2C						bool
+		//	Concurrent streams per HTTP/2 connection (default 100 if 0)
+		HTTP2_max_streams		int
+		//	Largest HTTP/2 frame read from clients: 16 KB - 16 MB (default 16 KB if 0)
+		HTTP2_max_frame_size	int
+	}
+	
+	Shutdown_hook func(ctx context.Context) error
+)
+
+//	Default server options (zero timeouts are disabled)
+func Default_options() Options {
+	return Options{
+		Read_header_timeout:	100 * time.Millisecond,
+		Idle_timeout:			30 * time.Second,
+		Shutdown_timeout:		30 * time.Second,
+	}
+}
+
+//	Apply server timeouts and header limits
+func (h *HTTP) Options(opt Options) *HTTP {
+	if opt.HTTP2_max_frame_size != 0 && (opt.HTTP2_max_frame_size < http2_min_frame_size || opt.HTTP2_max_frame_size > http2_max_frame_size) {
+		h.add_error(fmt.Errorf("HTTP/2 max frame size must be between %d and %d bytes: %d", http2_min_frame_size, http2_max_frame_size, opt.HTTP2_max_frame_size))
+	}
+	h.options = opt
+	return h
+}
+
+//	Apply hook executed after the server has stopped serving (hooks are executed in the order applied)
+func (h *HTTP) On_shutdown(hook Shutdown_hook) *HTTP {
+	h.shutdown_hooks = append(h.shutdown_hooks, hook)
+	return h
+}
+
+func (h *HTTP) new_server() *http.Server {
+	srv := &http.Server{
+		Addr:				fmt.Sprintf("%s:%d", h.listen_ip, h.listen_port),
+		Handler:			http.HandlerFunc(h.serve),
+		ReadTimeout:		h.options.Read_timeout,
+		ReadHeaderTimeout:	h.options.Read_header_timeout,
+		WriteTimeout:		h.options.Write_timeout,
+		IdleTimeout:		h.options.Idle_timeout,
+		MaxHeaderBytes:		h.options.Max_header_bytes,
+		HTTP2:				&http.HTTP2Config{
+			MaxConcurrentStreams:	h.options.HTTP2_max_streams,
+			MaxReadFrameSize:		h.options.HTTP2_max_frame_size,
+		},
+	}
+	if h.options.H2C {
+		//	HTTP/2 over TLS is still negotiated with ALPN if TLS is enabled
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = &protocols
+	}
+	return srv
+}
+
+//	Shutdown context without deadline if the shutdown timeout is disabled
+func (o Options) shutdown_context() (context.Context, context.CancelFunc){
+	if o.Shutdown_timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), o.Shutdown_timeout)
+}
+
+func (h *HTTP) run_shutdown_hooks(ctx context.Context) []error {
+	var errs []error
+	for _, hook := range h.shutdown_hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
\ No newline at end of file
@@ -0,0 +1,164 @@
+package serv
+
+import (
+	"io"
+	"net"
+	"time"
+	"errors"
+	"slices"
+	"context"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_run_context(t *testing.T){
+	t.Run("shutdown hooks", func(t *testing.T){
+		var got []string
+		
+		h := NewHTTP(tld, "127.0.0.1", 0).
+			Options(Options{
+				Read_header_timeout:	time.Second,
+				Shutdown_timeout:		time.Second,
+			}).
+			On_shutdown(func(ctx context.Context) error {
+				got = append(got, "sessions")
+				return nil
+			}).
+			On_shutdown(func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); !ok {
+					t.Error("Shutdown hook context has no deadline")
+				}
+				got = append(got, "logs")
+				return errors.New("flush logs")
+			})
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50 * time.Millisecond, cancel)
+		
+		err := h.Run_context(ctx)
+		if err == nil || err.Error() != "flush logs" {
+			t.Fatalf("Error want [flush logs] but got [%v]", err)
+		}
+		if want := []string{"sessions", "logs"}; !slices.Equal(want, got) {
+			t.Fatalf("Shutdown hooks want %v but got %v", want, got)
+		}
+	})
+	
+	t.Run("shutdown without timeout", func(t *testing.T){
+		h := NewHTTP(tld, "127.0.0.1", 0).
+			Options(Options{
+				Read_header_timeout:	time.Second,
+			}).
+			On_shutdown(func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); ok {
+					t.Error("Shutdown hook context want no deadline")
+				}
+				return ctx.Err()
+			})
+		
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50 * time.Millisecond, cancel)
+		
+		if err := h.Run_context(ctx); err != nil {
+			t.Fatalf("Shutdown want no error but got [%v]", err)
+		}
+	})
+	
+	t.Run("port in use", func(t *testing.T){
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		
+		h := NewHTTP(tld, "127.0.0.1", ln.Addr().(*net.TCPAddr).Port).
+			On_shutdown(func(ctx context.Context) error {
+				t.Error("Shutdown hook executed when server failed to start")
+				return nil
+			})
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+		defer cancel()
+		
+		if err := h.Run_context(ctx); err == nil {
+			t.Fatal("Expected error when port is in use")
+		}
+		if ctx.Err() != nil {
+			t.Fatal("Server did not return before context was done")
+		}
+	})
+}
+
+func Test_h2c(t *testing.T){
+	release := make(chan struct{})
+	
+	h := NewHTTP(tld, "127.0.0.1", 0).
+		Options(Options{
+			H2C:					true,
+			HTTP2_max_streams:		50,
+			HTTP2_max_frame_size:	1 << 20,
+		})
+	h.Subhost(sld).
+		Route(GET, "/stream", 0, func(w http.ResponseWriter, r *http.Request){
+			if r.ProtoMajor != 2 {
+				t.Errorf("Protocol want HTTP/2 but got %s", r.Proto)
+			}
+			io.WriteString(w, "first\n")
+			w.(http.Flusher).Flush()
+			<-release
+			io.WriteString(w, "second\n")
+		})
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = h.new_server()
+	srv.Start()
+	defer srv.Close()
+	
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{
+		Transport: &http.Transport{
+			Protocols: &protocols,
+		},
+	}
+	
+	r, err := http.NewRequest(http.MethodGet, srv.URL+"/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Host = base_url
+	res, err := client.Do(r)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer res.Body.Close()
+	if res.ProtoMajor != 2 {
+		t.Fatalf("Response want HTTP/2 but got %s", res.Proto)
+	}
+	
+	//	First chunk must arrive before the handler is released
+	b := make([]byte, 6)
+	if _, err := io.ReadFull(res.Body, b); err != nil || string(b) != "first\n" {
+		t.Fatalf("Flushed chunk want [first] but got [%s] %v", b, err)
+	}
+	close(release)
+	rest, err := io.ReadAll(res.Body)
+	if err != nil || string(rest) != "second\n" {
+		t.Fatalf("Body want [second] but got [%s] %v", rest, err)
+	}
+	
+	t.Run("frame size", func(t *testing.T){
+		h := NewHTTP(tld, "127.0.0.1", 0).
+			Options(Options{
+				HTTP2_max_frame_size:	1024,
+			})
+		if err := h.Validate(); err == nil || !strings.Contains(err.Error(), "HTTP/2 max frame size") {
+			t.Fatalf("Error want invalid frame size but got [%v]", err)
+		}
+	})
+}
\ No newline at end of file
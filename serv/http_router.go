@@ -0,0 +1,185 @@
+package serv
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*
+	Routes are compiled into a tree of path slugs per subhost.
+	Static slugs are looked up in a map and dynamic slugs are matched with their regex,
+	so matching cost grows with the path length instead of the number of routes.
+	
+	The first route in the route table that matches the path wins (like a linear scan),
+	which is resolved by comparing the route order (index in the route table).
+*/
+
+type (
+	router struct {
+		root		*router_node
+		regex		map[string]*regexp.Regexp
+	}
+	
+	router_node struct {
+		static		map[string]*router_node
+		dynamic		[]*router_edge
+		exact		*router_entry
+		prefix		*router_entry
+		min_order	int
+	}
+	
+	router_edge struct {
+		regex		*regexp.Regexp
+		node		*router_node
+	}
+	
+	router_entry struct {
+		route		*route
+		order		int
+	}
+	
+	router_match struct {
+		entry		*router_entry
+		slugs		[]string
+	}
+)
+
+func newRouter() *router {
+	return &router{
+		root:	newRouter_node(),
+		regex:	map[string]*regexp.Regexp{},
+	}
+}
+
+func newRouter_node() *router_node {
+	return &router_node{
+		static:		map[string]*router_node{},
+		min_order:	-1,
+	}
+}
+
+//	Insert route with its order in the route table
+func (rt *router) insert(r *route, order int){
+	n := rt.root
+	n.update_min_order(order)
+	
+	var param int
+	for _, slug := range r.slugs {
+		if slug[0] == ':' {
+			n = n.dynamic_child(rt.compile(r.param_regex[param]))
+			param++
+		} else {
+			n = n.static_child(slug)
+		}
+		n.update_min_order(order)
+	}
+	
+	entry := &router_entry{
+		route:	r,
+		order:	order,
+	}
+	if r.exact {
+		if n.exact == nil || n.exact.order > order {
+			n.exact = entry
+		}
+	} else {
+		if n.prefix == nil || n.prefix.order > order {
+			n.prefix = entry
+		}
+	}
+}
+
+//	Match path with the route that comes first in the route table
+func (rt *router) match(path string) (*route, []string){
+	var slugs []string
+	if path != "/" {
+		slugs = strings.Split(path[1:], "/")
+	}
+	
+	m := &router_match{}
+	rt.root.match(slugs, 0, nil, m)
+	if m.entry == nil {
+		return nil, nil
+	}
+	return m.entry.route, m.slugs
+}
+
+//	Compile anchored regex for a whole slug and share it between routes
+func (rt *router) compile(re string) *regexp.Regexp {
+	regex, ok := rt.regex[re]
+	if !ok {
+		regex = regexp.MustCompile("^"+re+"$")
+		rt.regex[re] = regex
+	}
+	return regex
+}
+
+func (n *router_node) match(slugs []string, i int, captures []string, m *router_match){
+	if n.prefix != nil && m.before(n.prefix) {
+		m.set(n.prefix, captures)
+	}
+	
+	if i == len(slugs) {
+		if n.exact != nil && m.before(n.exact) {
+			m.set(n.exact, captures)
+		}
+		return
+	}
+	
+	slug := slugs[i]
+	if child, ok := n.static[slug]; ok && m.before_order(child.min_order) {
+		child.match(slugs, i+1, captures, m)
+	}
+	
+	for _, edge := range n.dynamic {
+		if !m.before_order(edge.node.min_order) || !edge.regex.MatchString(slug) {
+			continue
+		}
+		edge.node.match(slugs, i+1, append(captures, slug), m)
+	}
+}
+
+func (n *router_node) static_child(slug string) *router_node {
+	child, ok := n.static[slug]
+	if !ok {
+		child = newRouter_node()
+		n.static[slug] = child
+	}
+	return child
+}
+
+func (n *router_node) dynamic_child(regex *regexp.Regexp) *router_node {
+	for _, edge := range n.dynamic {
+		if edge.regex == regex {
+			return edge.node
+		}
+	}
+	child := newRouter_node()
+	n.dynamic = append(n.dynamic, &router_edge{
+		regex:	regex,
+		node:	child,
+	})
+	return child
+}
+
+func (n *router_node) update_min_order(order int){
+	if n.min_order == -1 || order < n.min_order {
+		n.min_order = order
+	}
+}
+
+func (m *router_match) before(entry *router_entry) bool {
+	return m.before_order(entry.order)
+}
+
+func (m *router_match) before_order(order int) bool {
+	return order != -1 && (m.entry == nil || order < m.entry.order)
+}
+
+func (m *router_match) set(entry *router_entry, captures []string){
+	m.entry = entry
+	m.slugs = nil
+	if len(captures) > 0 {
+		m.slugs = append([]string{}, captures...)
+	}
+}
\ No newline at end of file
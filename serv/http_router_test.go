@@ -0,0 +1,145 @@
+package serv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"net/http"
+)
+
+/*
+	Test
+	# go test . -run Test_router -v
+	# go test . -bench Benchmark_router -benchmem
+*/
+
+func Test_router(t *testing.T){
+	for _, priority := range []bool{false, true} {
+		t.Run(fmt.Sprintf("priority routing %t", priority), func(t *testing.T){
+			s := test_router_subhost(20, priority)
+			
+			for _, path := range test_router_paths(20) {
+				path = strip_trailing_slash(path)
+				
+				want_route, want_slugs := test_match_linear(s.routes, path)
+				got_route, got_slugs := s.router.match(path)
+				
+				if want_route != got_route {
+					t.Fatalf("Route mismatch on %s\nwant: %s\ngot: %s", path, test_route_string(want_route), test_route_string(got_route))
+				}
+				if !slices.Equal(want_slugs, got_slugs) {
+					t.Fatalf("Slugs mismatch on %s\nwant: %v\ngot: %v", path, want_slugs, got_slugs)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_router(b *testing.B){
+	s := test_router_subhost(50, false)
+	paths := test_router_paths(50)
+	
+	b.Logf("Routes: %d, paths: %d", len(s.routes), len(paths))
+	
+	b.Run("linear", func(b *testing.B){
+		for i := 0; b.Loop(); i++ {
+			test_match_linear(s.routes, paths[i % len(paths)])
+		}
+	})
+	
+	b.Run("tree", func(b *testing.B){
+		for i := 0; b.Loop(); i++ {
+			s.router.match(paths[i % len(paths)])
+		}
+	})
+}
+
+//	Subhost with a route table of n*6 routes
+func test_router_subhost(n int, priority bool) *Subhost {
+	h := NewHTTP(tld, "", 0)
+	s := h.Subhost(sld)
+	
+	handler := func(w http.ResponseWriter, r *http.Request){}
+	
+	for i := range n {
+		s.Route(GET, fmt.Sprintf("/section%d", i), 0, handler).
+			Route_exact(GET, fmt.Sprintf("/section%d/page/about", i), 0, handler).
+			Route(GET, fmt.Sprintf("/api%d/user/:id<int>/files", i), 0, handler).
+			Route_exact(ALL, fmt.Sprintf("/api%d/user/:name", i), 0, handler).
+			Route_exact(GET, fmt.Sprintf("/static%d/:file", i), 0, handler).
+			Route(POST, fmt.Sprintf("/static%d/:slug/:slug", i), 0, handler)
+	}
+	s.Route_blind(ALL, "/section1/hidden").
+		Route(GET, "/", 0, handler)
+	
+	if priority {
+		s.sort_priority()
+	}
+	return s
+}
+
+func test_router_paths(n int) []string {
+	paths := []string{"/", "/unknown", "/unknown/path"}
+	for i := range n {
+		paths = append(paths,
+			fmt.Sprintf("/section%d", i),
+			fmt.Sprintf("/section%d/", i),
+			fmt.Sprintf("/section%d-more", i),
+			fmt.Sprintf("/section%d/page/about", i),
+			fmt.Sprintf("/section%d/page/about/more", i),
+			fmt.Sprintf("/section%d/hidden/path", i),
+			fmt.Sprintf("/api%d/user/42/files", i),
+			fmt.Sprintf("/api%d/user/42/files/deep/path", i),
+			fmt.Sprintf("/api%d/user/john", i),
+			fmt.Sprintf("/api%d/user/john/files", i),
+			fmt.Sprintf("/static%d/file.json", i),
+			fmt.Sprintf("/static%d/File.JSON", i),
+			fmt.Sprintf("/static%d/dir/file.json", i),
+			fmt.Sprintf("/static%d/dir/sub/file.json", i),
+		)
+	}
+	return paths
+}
+
+func test_route_string(r *route) string {
+	if r == nil {
+		return "<nil>"
+	}
+	return r.string()
+}
+
+//	Reference implementation: linear scan of the route table
+func test_match_linear(routes routes, path string) (*route, []string){
+	for _, route := range routes {
+		if route.regex != nil {
+			matches := route.regex.FindStringSubmatch(path)
+			if len(matches) > 0 {
+				if route.depth != 0 && !test_match_path_depth(path, matches[0]) {
+					continue
+				}
+				return route, matches[1:]
+			}
+		} else if test_match_path(path, route) {
+			return route, nil
+		}
+	}
+	return nil, nil
+}
+
+func test_match_path(path string, route *route) bool {
+	if route.exact {
+		return path == route.pattern
+	}
+	if !strings.HasPrefix(path, route.pattern) {
+		return false
+	}
+	if route.depth != 0 {
+		return test_match_path_depth(path, route.pattern)
+	}
+	return true
+}
+
+func test_match_path_depth(path, pattern string) bool {
+	return path == pattern || path[len(pattern)] == '/'
+}
\ No newline at end of file
@@ -0,0 +1,133 @@
+package serv
+
+import (
+	"log"
+	"errors"
+	"strconv"
+	"strings"
+	"context"
+	"net/http"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json/jsontext"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+const (
+	CSP_NONCE = "{nonce}"
+	
+	csp_report_limit_kb = 64
+)
+
+type Security_options struct {
+	//	Strict-Transport-Security max-age in seconds (0 to disable)
+	HSTS_max_age		int
+	HSTS_subdomains		bool
+	HSTS_preload		bool
+	//	Content-Security-Policy with {nonce} placeholder replaced by a per-request nonce:
+	//	"default-src 'self'; script-src 'self' 'nonce-{nonce}'"
+	CSP					string
+	//	Send Content-Security-Policy-Report-Only to collect violations without enforcing the policy
+	CSP_report_only		bool
+	//	URI receiving violation reports (see CSP_report)
+	CSP_report_uri		string
+	//	Referrer-Policy (default "strict-origin-when-cross-origin")
+	Referrer_policy		string
+	//	Permissions-Policy: "camera=(), microphone=(), geolocation=()"
+	Permissions_policy	string
+	//	X-Frame-Options: "DENY" or "SAMEORIGIN"
+	Frame_options		string
+}
+
+//	Apply security headers to responses with optional per-request CSP nonce (see CSP_nonce)
+func Security_headers(opts Security_options) Adapter {
+	if opts.Referrer_policy == "" {
+		opts.Referrer_policy = "strict-origin-when-cross-origin"
+	}
+	
+	var hsts string
+	if opts.HSTS_max_age > 0 {
+		hsts = "max-age="+strconv.Itoa(opts.HSTS_max_age)
+		if opts.HSTS_subdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTS_preload {
+			hsts += "; preload"
+		}
+	}
+	
+	csp := opts.CSP
+	if csp != "" && opts.CSP_report_uri != "" {
+		csp = strings.TrimRight(csp, "; ")+"; report-uri "+opts.CSP_report_uri
+	}
+	csp_header := "Content-Security-Policy"
+	if opts.CSP_report_only {
+		csp_header = "Content-Security-Policy-Report-Only"
+	}
+	csp_nonce := strings.Contains(csp, CSP_NONCE)
+	
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request){
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("Referrer-Policy", opts.Referrer_policy)
+			if hsts != "" {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+			if opts.Permissions_policy != "" {
+				header.Set("Permissions-Policy", opts.Permissions_policy)
+			}
+			if opts.Frame_options != "" {
+				header.Set("X-Frame-Options", opts.Frame_options)
+			}
+			
+			if csp_nonce {
+				nonce := newNonce()
+				header.Set(csp_header, strings.ReplaceAll(csp, CSP_NONCE, nonce))
+				r = r.WithContext(context.WithValue(r.Context(), ctx_nonce, nonce))
+			} else if csp != "" {
+				header.Set(csp_header, csp)
+			}
+			
+			next(w, r)
+		}
+	}
+}
+
+//	Get CSP nonce for inline scripts and styles in templates: <script nonce="{{.Nonce}}">
+func CSP_nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(ctx_nonce).(string)
+	return nonce
+}
+
+//	Collect CSP violation reports (application/csp-report or application/reports+json) and log them as one line
+func CSP_report(logger *log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request){
+		b, err := req.Post_limit_read(w, r, csp_report_limit_kb)
+		if err != nil {
+			var max_bytes *http.MaxBytesError
+			if errors.As(err, &max_bytes) {
+				Error(w, r, http.StatusRequestEntityTooLarge, err)
+			} else {
+				Error(w, r, http.StatusBadRequest, err)
+			}
+			return
+		}
+		
+		report := jsontext.Value(b)
+		if err := report.Compact(); err != nil {
+			Error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		
+		logger.Printf("CSP report from %s (Request ID %s): %s", req.Get_client_IP(r), req.Request_ID(r), report)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+//	Generate 128 bit nonce
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
\ No newline at end of file
@@ -0,0 +1,97 @@
+package serv
+
+import (
+	"log"
+	"bytes"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_security_headers(t *testing.T){
+	var nonce string
+	
+	var buf bytes.Buffer
+	
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Use(Security_headers(Security_options{
+			HSTS_max_age:		63072000,
+			HSTS_subdomains:	true,
+			CSP:				"default-src 'self'; script-src 'self' 'nonce-{nonce}';",
+			CSP_report_uri:		"/csp-report",
+			Permissions_policy:	"camera=()",
+		})).
+		Route_exact(GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			nonce = CSP_nonce(r)
+		}).
+		Route(POST, "/csp-report", 0, CSP_report(log.New(&buf, "", 0)))
+	h.Subhost("report.").
+		Use(Security_headers(Security_options{
+			CSP:				"default-src 'self'",
+			CSP_report_only:	true,
+		})).
+		Route(GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			nonce = CSP_nonce(r)
+		})
+	
+	handler := h.test_handler()
+	
+	t.Run("headers", func(t *testing.T){
+		var nonces []string
+		for range 2 {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test_request(t, http.MethodGet, base_url+"/"))
+			
+			if len(nonce) != 24 {
+				t.Fatalf("Nonce want 24 characters but got [%s]", nonce)
+			}
+			test_header(t, w, "Content-Security-Policy", "default-src 'self'; script-src 'self' 'nonce-"+nonce+"'; report-uri /csp-report")
+			test_header(t, w, "Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+			test_header(t, w, "X-Content-Type-Options", "nosniff")
+			test_header(t, w, "Referrer-Policy", "strict-origin-when-cross-origin")
+			test_header(t, w, "Permissions-Policy", "camera=()")
+			test_header(t, w, "X-Frame-Options", "")
+			nonces = append(nonces, nonce)
+		}
+		if nonces[0] == nonces[1] {
+			t.Fatal("Nonce want unique per request")
+		}
+	})
+	
+	t.Run("report only", func(t *testing.T){
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, test_request(t, http.MethodGet, "report."+tld+"/"))
+		test_header(t, w, "Content-Security-Policy-Report-Only", "default-src 'self'")
+		test_header(t, w, "Content-Security-Policy", "")
+		test_header(t, w, "Strict-Transport-Security", "")
+		if nonce != "" {
+			t.Fatalf("Nonce want empty without placeholder but got [%s]", nonce)
+		}
+	})
+	
+	t.Run("report", func(t *testing.T){
+		for body, want_status := range map[string]int{
+			"{\n  \"csp-report\": {\"blocked-uri\": \"inline\"}\n}":	http.StatusNoContent,
+			"not json":												http.StatusBadRequest,
+			strings.Repeat(" ", 65*1024):							http.StatusRequestEntityTooLarge,
+		} {
+			buf.Reset()
+			r, err := http.NewRequest(http.MethodPost, "//"+base_url+"/csp-report", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/csp-report")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			
+			if w.Code != want_status {
+				t.Fatalf("Report want HTTP %d but got %d", want_status, w.Code)
+			}
+			if want_status == http.StatusNoContent && !strings.Contains(buf.String(), `{"csp-report":{"blocked-uri":"inline"}}`) {
+				t.Fatalf("Report not logged: %s", buf.String())
+			}
+		}
+	})
+}
\ No newline at end of file
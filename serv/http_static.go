@@ -0,0 +1,230 @@
+package serv
+
+import (
+	"io"
+	"fmt"
+	"html"
+	"mime"
+	"path"
+	"sync"
+	"bytes"
+	"io/fs"
+	"strconv"
+	"strings"
+	"net/url"
+	"net/http"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type (
+	Static_options struct {
+		//	Index file served in directories (empty to disable)
+		Index			string
+		//	Show directory listing if the directory has no index file
+		List_dirs		bool
+		//	Serve precompressed .gz sibling files if accepted by the client
+		Precompressed	bool
+		//	Cache-Control per file extension: ".css" -> "public, max-age=31536000"
+		Cache_control	map[string]string
+		//	Cache-Control on files with extensions not in Cache_control
+		Cache_default	string
+	}
+	
+	static_handler struct {
+		subhost		*Subhost
+		fsys		fs.FS
+		strip		string
+		opts		Static_options
+		etags		sync.Map
+	}
+)
+
+//	Apply static file route serving files from fs.FS (works with embed.FS)
+func (s *Subhost) Static(prefix string, fsys fs.FS, opts Static_options) *Subhost {
+	if err := validate_pattern(prefix); err != nil {
+		s.add_error(err)
+		return s
+	}
+	prefix = strip_trailing_slash(prefix)
+	
+	sh := &static_handler{
+		subhost:	s,
+		fsys:		fsys,
+		strip:		strings.TrimSuffix(s.path_prefix+prefix, "/"),
+		opts:		opts,
+	}
+	return s.Route(GET, prefix, 0, sh.serve)
+}
+
+func (sh *static_handler) serve(w http.ResponseWriter, r *http.Request){
+	name, ok := sh.file_name(r.URL.Path)
+	if !ok {
+		sh.subhost.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+	
+	info, err := fs.Stat(sh.fsys, name)
+	if err != nil {
+		sh.subhost.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+	
+	if info.IsDir() {
+		if sh.opts.Index != "" {
+			index := path.Join(name, sh.opts.Index)
+			if info, err := fs.Stat(sh.fsys, index); err == nil && !info.IsDir() {
+				sh.serve_file(w, r, index, info)
+				return
+			}
+		}
+		if sh.opts.List_dirs {
+			sh.serve_dir(w, r, name)
+			return
+		}
+		sh.subhost.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+	
+	sh.serve_file(w, r, name, info)
+}
+
+//	Serve file with ETag, Last-Modified, conditional GET and Range requests
+func (sh *static_handler) serve_file(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo){
+	header := w.Header()
+	
+	ext := path.Ext(name)
+	if content_type := mime.TypeByExtension(ext); content_type != "" {
+		header.Set("Content-Type", content_type)
+	}
+	if cache_control := sh.cache_control(ext); cache_control != "" {
+		header.Set("Cache-Control", cache_control)
+	}
+	
+	//	Precompressed sibling file
+	if sh.opts.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if accept_encoding(r, "gzip") {
+			if gz_info, err := fs.Stat(sh.fsys, name+".gz"); err == nil && !gz_info.IsDir() {
+				if header.Get("Content-Type") == "" {
+					header.Set("Content-Type", "application/octet-stream")
+				}
+				header.Set("Content-Encoding", "gzip")
+				name, info = name+".gz", gz_info
+			}
+		}
+	}
+	
+	f, err := sh.fsys.Open(name)
+	if err != nil {
+		sh.subhost.error(w, r, http.StatusNotFound, nil)
+		return
+	}
+	defer f.Close()
+	
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			sh.subhost.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	
+	etag, err := sh.etag(name, info, content)
+	if err != nil {
+		sh.subhost.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	header.Set("ETag", etag)
+	
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+func (sh *static_handler) serve_dir(w http.ResponseWriter, r *http.Request, name string){
+	entries, err := fs.ReadDir(sh.fsys, name)
+	if err != nil {
+		sh.subhost.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	
+	base := strings.TrimSuffix(r.URL.Path, "/")+"/"
+	
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<title>%s</title>\n<pre>\n", html.EscapeString(base))
+	for _, entry := range entries {
+		entry_name := entry.Name()
+		if strings.HasPrefix(entry_name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			entry_name += "/"
+		}
+		href := (&url.URL{Path: base+entry_name}).String()
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(entry_name))
+	}
+	fmt.Fprint(w, "</pre>\n")
+}
+
+//	Get file name in fs.FS from URL path (hidden files and directories are not served)
+func (sh *static_handler) file_name(url_path string) (string, bool){
+	if !strings.HasPrefix(url_path, sh.strip) {
+		return "", false
+	}
+	name := strings.TrimPrefix(path.Clean("/"+url_path[len(sh.strip):]), "/")
+	if name == "" {
+		return ".", true
+	}
+	for _, slug := range strings.Split(name, "/") {
+		if strings.HasPrefix(slug, ".") {
+			return "", false
+		}
+	}
+	return name, fs.ValidPath(name)
+}
+
+//	ETag from size and modification time, or content hash if the file has no modification time (embed.FS)
+func (sh *static_handler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error){
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	if etag, ok := sh.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"`+hex.EncodeToString(hash.Sum(nil)[:16])+`"`
+	sh.etags.Store(name, etag)
+	return etag, nil
+}
+
+func (sh *static_handler) cache_control(ext string) string {
+	if cache_control, ok := sh.opts.Cache_control[ext]; ok {
+		return cache_control
+	}
+	return sh.opts.Cache_default
+}
+
+//	Check if content coding is accepted by the client (with q-value above 0)
+func accept_encoding(r *http.Request, coding string) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(encoding, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		if !found {
+			return true
+		}
+		value, err := strconv.ParseFloat(q, 64)
+		return err == nil && value > 0
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,146 @@
+package serv
+
+import (
+	"time"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"testing/fstest"
+)
+
+func Test_static(t *testing.T){
+	mod_time := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	
+	fsys := fstest.MapFS{
+		"index.html":			{Data: []byte("<h1>index</h1>"), ModTime: mod_time},
+		"css/site.css":			{Data: []byte("body{}"), ModTime: mod_time},
+		"js/app.js":			{Data: []byte("console.log(1)")},
+		"js/app.js.gz":			{Data: []byte("gzipped")},
+		"docs/readme.txt":		{Data: []byte("0123456789"), ModTime: mod_time},
+		"docs/.secret":			{Data: []byte("secret")},
+		".env":					{Data: []byte("secret")},
+	}
+	
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Static("/assets", fsys, Static_options{
+			Index:			"index.html",
+			Precompressed:	true,
+			Cache_control:	map[string]string{
+				".css":	"public, max-age=31536000",
+			},
+			Cache_default:	"no-cache",
+		})
+	h.Subhost("list.").
+		Static("/", fsys, Static_options{
+			List_dirs:		true,
+		})
+	
+	handler := h.test_handler()
+	
+	request := func(url string, header map[string]string) *httptest.ResponseRecorder {
+		r := test_request(t, http.MethodGet, url)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	
+	t.Run("file", func(t *testing.T){
+		w := request(base_url+"/assets/css/site.css", nil)
+		if w.Code != http.StatusOK || w.Body.String() != "body{}" {
+			t.Fatalf("want [200] [body{}] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		test_header(t, w, "Content-Type", "text/css; charset=utf-8")
+		test_header(t, w, "Cache-Control", "public, max-age=31536000")
+		test_header(t, w, "Last-Modified", mod_time.Format(http.TimeFormat))
+		if w.Header().Get("ETag") == "" {
+			t.Fatal("ETag is missing")
+		}
+	})
+	
+	t.Run("index", func(t *testing.T){
+		w := request(base_url+"/assets/", nil)
+		if w.Code != http.StatusOK || w.Body.String() != "<h1>index</h1>" {
+			t.Fatalf("want [200] [<h1>index</h1>] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		test_header(t, w, "Cache-Control", "no-cache")
+	})
+	
+	t.Run("conditional GET", func(t *testing.T){
+		etag := request(base_url+"/assets/css/site.css", nil).Header().Get("ETag")
+		
+		w := request(base_url+"/assets/css/site.css", map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("If-None-Match want [304] but got [%d]", w.Code)
+		}
+		
+		w = request(base_url+"/assets/css/site.css", map[string]string{"If-Modified-Since": mod_time.Format(http.TimeFormat)})
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("If-Modified-Since want [304] but got [%d]", w.Code)
+		}
+	})
+	
+	t.Run("content hash ETag without modification time", func(t *testing.T){
+		w1 := request(base_url+"/assets/js/app.js", nil)
+		w2 := request(base_url+"/assets/js/app.js", map[string]string{"If-None-Match": w1.Header().Get("ETag")})
+		if w1.Header().Get("ETag") == "" || w2.Code != http.StatusNotModified {
+			t.Fatalf("want ETag and [304] but got [%s] [%d]", w1.Header().Get("ETag"), w2.Code)
+		}
+	})
+	
+	t.Run("range", func(t *testing.T){
+		w := request(base_url+"/assets/docs/readme.txt", map[string]string{"Range": "bytes=2-5"})
+		if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+			t.Fatalf("want [206] [2345] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		test_header(t, w, "Content-Range", "bytes 2-5/10")
+	})
+	
+	t.Run("precompressed", func(t *testing.T){
+		w := request(base_url+"/assets/js/app.js", map[string]string{"Accept-Encoding": "br, gzip"})
+		if w.Body.String() != "gzipped" {
+			t.Fatalf("want [gzipped] but got [%s]", w.Body.String())
+		}
+		test_header(t, w, "Content-Encoding", "gzip")
+		test_header(t, w, "Content-Type", "text/javascript; charset=utf-8")
+		test_header(t, w, "Vary", "Accept-Encoding")
+		
+		w = request(base_url+"/assets/js/app.js", map[string]string{"Accept-Encoding": "gzip;q=0"})
+		if w.Body.String() != "console.log(1)" {
+			t.Fatalf("want [console.log(1)] but got [%s]", w.Body.String())
+		}
+	})
+	
+	t.Run("not found", func(t *testing.T){
+		for _, url := range []string{
+			base_url+"/assets/unknown.css",
+			base_url+"/assets/.env",
+			base_url+"/assets/docs/.secret",
+			base_url+"/assets/docs",
+			base_url+"/assets/../.env",
+		} {
+			if w := request(url, nil); w.Code != http.StatusNotFound {
+				t.Fatalf("%s want [404] but got [%d]", url, w.Code)
+			}
+		}
+	})
+	
+	t.Run("directory listing", func(t *testing.T){
+		w := request("list."+tld+"/docs", nil)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, `<a href="/docs/readme.txt">readme.txt</a>`) || strings.Contains(body, ".secret") {
+			t.Fatalf("Unexpected directory listing [%d] %s", w.Code, body)
+		}
+	})
+}
+
+func test_header(t *testing.T, w *httptest.ResponseRecorder, key, want string){
+	t.Helper()
+	if got := w.Header().Get(key); got != want {
+		t.Fatalf("Header %s want [%s] but got [%s]", key, want, got)
+	}
+}
\ No newline at end of file
@@ -1,7 +1,8 @@
 package serv
 
 import (
-	"log"
+	"fmt"
+	"sync"
 	"slices"
 	"strings"
 	"regexp"
@@ -11,29 +12,46 @@ import (
 const (
 	ALL Method 		= "*"
 	GET Method		= http.MethodGet
+	HEAD Method		= http.MethodHead
 	POST Method		= http.MethodPost
+	PUT Method		= http.MethodPut
+	PATCH Method	= http.MethodPatch
 	DELETE Method	= http.MethodDelete
+	OPTIONS Method	= http.MethodOptions
 	
 	pattern_slug	= ":slug"
 	pattern_file 	= ":file"
 	
+	param_int		= "int"
+	param_uuid		= "uuid"
+	
 	re_slug_pattern = `([^/]+)`
 	re_file_pattern = `([a-z\d\-_]+\.[a-z]{1,4})`
+	re_int_pattern	= `(-?\d{1,18})`
+	re_uuid_pattern	= `([\da-fA-F]{8}-[\da-fA-F]{4}-[\da-fA-F]{4}-[\da-fA-F]{4}-[\da-fA-F]{12})`
 )
 
 var (
 	re_slug 		= regexp.MustCompile(`^[\p{L}\d.\-_]*$`)
+	re_param_name	= regexp.MustCompile(`^[a-z][a-z\d_]*$`)
 )
 
 type (
 	Method 			string
 	
 	Subhost struct {
+		sld					string
 		path_prefix			string
 		map_routes 			map_routes
 		map_exact			map_exact
 		routes 				routes
+		router 				*router
 		priority_routing	bool
+		adapters			[]Adapter
+		tls					*tls_cert
+		error_handler		Error_handler
+		cors				*CORS
+		errs				[]error
 	}
 	
 	map_routes 		map[string]route_handlers
@@ -49,6 +67,8 @@ type (
 		pattern 	string
 		exact		bool
 		slugs 		[]string
+		params 		[]string
+		param_regex	[]string
 		depth 		int
 		regex 		*regexp.Regexp
 	}
@@ -59,141 +79,209 @@ type (
 		timeout 	int
 		blind		bool
 		handler		http.HandlerFunc
+		adapters	[]Adapter
+		group		*Group
+		once		sync.Once
+		chain		http.HandlerFunc
 	}
 )
 
+//	Apply middleware to all routes on subhost (executed in the order applied after HTTP middleware)
+func (s *Subhost) Use(adapters ...Adapter) *Subhost {
+	s.adapters = append(s.adapters, adapters...)
+	return s
+}
+
 func (s *Subhost) Priority_routing() *Subhost {
 	s.priority_routing = true
 	return s
 }
 
-//	Apply route pattern exact
-func (s *Subhost) Route_exact(method Method, pattern string, timeout int, handler http.HandlerFunc) *Subhost {
-	return s.route(method, pattern, timeout, handler, true, false)
+//	Apply route pattern exact with optional route middleware
+func (s *Subhost) Route_exact(method Method, pattern string, timeout int, handler http.HandlerFunc, adapters ...Adapter) *Subhost {
+	s.route(method, pattern, timeout, handler, adapters, nil, true, false)
+	return s
 }
 
-//	Apply route pattern
-func (s *Subhost) Route(method Method, pattern string, timeout int, handler http.HandlerFunc) *Subhost {
-	return s.route(method, pattern, timeout, handler, false, false)
+//	Apply route pattern with optional route middleware
+func (s *Subhost) Route(method Method, pattern string, timeout int, handler http.HandlerFunc, adapters ...Adapter) *Subhost {
+	s.route(method, pattern, timeout, handler, adapters, nil, false, false)
+	return s
 }
 
 //	Apply blind route pattern (HTTP 404)
 func (s *Subhost) Route_blind(method Method, pattern string) *Subhost {
 	var handler http.HandlerFunc
-	return s.route(method, pattern, 0, handler, false, true)
+	s.route(method, pattern, 0, handler, nil, nil, false, true)
+	return s
 }
 
-func (s *Subhost) route(method Method, pattern string, timeout int, handler http.HandlerFunc, exact, blind bool) *Subhost {
+func (s *Subhost) route(method Method, pattern string, timeout int, handler http.HandlerFunc, adapters []Adapter, group *Group, exact, blind bool){
 	if s.path_prefix != "" {
 		pattern = s.path_prefix+pattern
 	}
 	
-	validate_pattern(pattern)
+	if err := validate_pattern(pattern); err != nil {
+		s.add_error(err)
+		return
+	}
 	
 	key_method 	:= string(method)
 	timeout 	= timeout_min(timeout)
 	
 	if existing_route, ok := s.map_routes[pattern]; ok {
-		s.validate_existing_route(method, pattern, exact, existing_route)
+		if err := s.validate_existing_route(method, pattern, exact, existing_route); err != nil {
+			s.add_error(err)
+			return
+		}
 		
 		existing_route[key_method] = &route_handler{
 			timeout:	timeout,
 			blind:		blind,
 			handler:	handler,
+			adapters:	adapters,
+			group:		group,
 		}
 	} else {
+		parsed, err := parse_route_pattern(pattern, exact)
+		if err != nil {
+			s.add_error(err)
+			return
+		}
+		
 		methods := route_handlers{
 			key_method: &route_handler{
 				timeout:	timeout,
 				blind:		blind,
 				handler:	handler,
+				adapters:	adapters,
+				group:		group,
 			},
 		}
 		
 		s.map_routes[pattern]	= methods
 		s.map_exact[pattern]	= exact
-		s.routes = append(s.routes, &route{
-			route_pattern:	parse_route_pattern(pattern, exact),
+		r := &route{
+			route_pattern:	parsed,
 			methods:		methods,
-		})
+		}
+		s.router.insert(r, len(s.routes))
+		s.routes = append(s.routes, r)
 	}
-	
-	return s
 }
 
 func (s *Subhost) sort_priority(){
-	slices.SortFunc(s.routes, func(a, b *route) int {
-		a_length	:= len(a.slugs)
-		b_length	:= len(b.slugs)
-		min_length	:= min(a_length, b_length)
+	defer s.build_router()
+	
+	slices.SortFunc(s.routes, compare_priority)
+}
+
+//	Compare routes by matching priority
+func compare_priority(a, b *route) int {
+	a_length	:= len(a.slugs)
+	b_length	:= len(b.slugs)
+	min_length	:= min(a_length, b_length)
+	
+	for i := range min_length {
+		a_slug := a.slugs[i]
+		b_slug := b.slugs[i]
 		
-		for i := range min_length {
-			a_slug := a.slugs[i]
-			b_slug := b.slugs[i]
-			
-			a_dynamic := strings.HasPrefix(a_slug, ":")
-			b_dynamic := strings.HasPrefix(b_slug, ":")
-			
-			if a_slug == b_slug {
-				continue
-			}
-			
-			//	Same type (dynamic/static)
-			if a_dynamic == b_dynamic {
-				//	Both dynamic
-				if a_dynamic {
-					//	:file comes first
-					if a_slug == pattern_file {
-						return -1
-					}
-					if b_slug == pattern_file {
-						return 1
-					}
-				}
-				
-				//	Alphabetical sort
-				return strings.Compare(a_slug, b_slug)
-			} else {
-				//	Static comes first
-				if !a_dynamic && b_dynamic {
-					return -1
-				}
-				if a_dynamic && !b_dynamic {
-					return 1
-				}
-			}
-		}
+		a_dynamic := strings.HasPrefix(a_slug, ":")
+		b_dynamic := strings.HasPrefix(b_slug, ":")
 		
-		//	Deeper path comes first
-		if a_length != b_length {
-			return b_length - a_length
+		if a_slug == b_slug {
+			continue
 		}
 		
-		//	Extact paths come first
-		if a.exact != b.exact {
-			if a.exact {
+		//	Same type (dynamic/static)
+		if a_dynamic == b_dynamic {
+			//	Both dynamic
+			if a_dynamic {
+				//	:file comes first, then typed parameters
+				a_rank := param_rank(a_slug)
+				b_rank := param_rank(b_slug)
+				if a_rank != b_rank {
+					return a_rank - b_rank
+				}
+			}
+			
+			//	Alphabetical sort
+			return strings.Compare(a_slug, b_slug)
+		} else {
+			//	Static comes first
+			if !a_dynamic && b_dynamic {
 				return -1
 			}
-			return 1
+			if a_dynamic && !b_dynamic {
+				return 1
+			}
 		}
-		return 0
-	})
+	}
+	
+	//	Deeper path comes first
+	if a_length != b_length {
+		return b_length - a_length
+	}
+	
+	//	Extact paths come first
+	if a.exact != b.exact {
+		if a.exact {
+			return -1
+		}
+		return 1
+	}
+	return 0
 }
 
-func (s *Subhost) validate_existing_route(method Method, pattern string, exact bool, existing_route route_handlers){
+//	Rebuild router after the order of routes has changed
+func (s *Subhost) build_router(){
+	s.router = newRouter()
+	for i, r := range s.routes {
+		s.router.insert(r, i)
+	}
+}
+
+func (s *Subhost) validate_existing_route(method Method, pattern string, exact bool, existing_route route_handlers) error {
 	if _, ok := existing_route[string(method)]; ok {
-		log.Fatalf("Route is duplicate: %s %s", method, pattern)
+		return fmt.Errorf("Route is duplicate: %s %s", method, pattern)
 	}
 	
 	if method == ALL {
-		log.Fatalf("Route is redundant: %s %s", method, pattern)
+		return fmt.Errorf("Route is redundant: %s %s", method, pattern)
 	} else if _, ok := existing_route[string(ALL)]; ok {
-		log.Fatalf("Route is redundant: %s %s", method, pattern)
+		return fmt.Errorf("Route is redundant: %s %s", method, pattern)
 	}
 	
 	if s.map_exact[pattern] != exact {
-		log.Fatal("Routes with exact/prefix can not be mixed")
+		return fmt.Errorf("Routes with exact/prefix can not be mixed: %s", pattern)
 	}
+	return nil
+}
+
+//	Collect registration error (returned by Validate)
+func (s *Subhost) add_error(err error){
+	s.errs = append(s.errs, s.wrap_error(err))
+}
+
+func (s *Subhost) wrap_error(err error) error {
+	sld := s.sld
+	if sld == "" {
+		sld = "(apex)"
+	}
+	return fmt.Errorf("Subhost %s: %w", sld, err)
+}
+
+//	Wrap route handler in middleware chain once: HTTP, subhost, group (outer to inner) and route middleware
+func (rh *route_handler) handler_chain(http_adapters, subhost_adapters []Adapter) http.HandlerFunc {
+	rh.once.Do(func(){
+		rh.chain = chain(rh.handler, rh.adapters)
+		for g := rh.group; g != nil; g = g.parent {
+			rh.chain = chain(rh.chain, g.adapters)
+		}
+		rh.chain = chain(rh.chain, subhost_adapters)
+		rh.chain = chain(rh.chain, http_adapters)
+	})
+	return rh.chain
 }
 
 func (r *route) string() string {
@@ -206,20 +294,22 @@ func (r *route) string() string {
 	return " "+r.pattern
 }
 
-func parse_route_pattern(pattern string, exact bool) route_pattern {
+func parse_route_pattern(pattern string, exact bool) (route_pattern, error){
 	pattern = strip_trailing_slash(pattern)
 	
 	if pattern == "/" {
 		return route_pattern{
 			pattern:	pattern,
 			exact:		exact,
-		}
+		}, nil
 	}
 	
 	var (
 		re 			string
 		has_regex 	bool
 		regex 		*regexp.Regexp
+		params 		[]string
+		param_regex	[]string
 	)
 	
 	slugs := strings.Split(strings.TrimLeft(pattern, "/"), "/")
@@ -227,7 +317,7 @@ func parse_route_pattern(pattern string, exact bool) route_pattern {
 	
 	for i, slug := range slugs {
 		if slug == "" {
-			log.Fatalf("Route slug can not be empty: %s", pattern)
+			return route_pattern{}, fmt.Errorf("Route slug can not be empty: %s", pattern)
 		}
 		
 		if slug[0] == ':' {
@@ -235,17 +325,30 @@ func parse_route_pattern(pattern string, exact bool) route_pattern {
 			switch slug {
 			case pattern_slug:
 				re += "/"+re_slug_pattern
+				params = append(params, "")
+				param_regex = append(param_regex, re_slug_pattern)
 			case pattern_file:
 				if !exact || depth-1 != i {
-					log.Fatalf("Route file can only be the last level in combination with exact: %s", pattern)
+					return route_pattern{}, fmt.Errorf("Route file can only be the last level in combination with exact: %s", pattern)
 				}
 				re += "/"+re_file_pattern
+				params = append(params, "")
+				param_regex = append(param_regex, re_file_pattern)
 			default:
-				log.Fatalf("Invalid regex parameter: %s", slug)
+				name, re_param, err := parse_route_param(slug, pattern)
+				if err != nil {
+					return route_pattern{}, err
+				}
+				if name != "" && slices.Contains(params, name) {
+					return route_pattern{}, fmt.Errorf("Route parameter is duplicate: %s (%s)", name, pattern)
+				}
+				re += "/"+re_param
+				params = append(params, name)
+				param_regex = append(param_regex, re_param)
 			}
 		} else {
 			if !re_slug.MatchString(slug) {
-				log.Fatalf("Invalid chars in slug: %s (%s)", slug, pattern)
+				return route_pattern{}, fmt.Errorf("Invalid chars in slug: %s (%s)", slug, pattern)
 			}
 			
 			re += "/"+slug
@@ -264,18 +367,65 @@ func parse_route_pattern(pattern string, exact bool) route_pattern {
 		pattern:	pattern,
 		exact:		exact,
 		slugs:		slugs,
+		params:		params,
+		param_regex:	param_regex,
 		depth:		depth,
 		regex:		regex,
+	}, nil
+}
+
+//	Parse named route parameter with optional type: :name, :name<int>, :name<uuid> or :name<regex>
+func parse_route_param(slug, pattern string) (string, string, error){
+	name, param_type, typed := strings.Cut(slug[1:], "<")
+	if !re_param_name.MatchString(name) {
+		return "", "", fmt.Errorf("Invalid regex parameter: %s (%s)", slug, pattern)
+	}
+	if !typed {
+		return name, re_slug_pattern, nil
+	}
+	
+	param_type, ok := strings.CutSuffix(param_type, ">")
+	if !ok || param_type == "" {
+		return "", "", fmt.Errorf("Invalid regex parameter type: %s (%s)", slug, pattern)
+	}
+	
+	switch param_type {
+	case param_int:
+		return name, re_int_pattern, nil
+	case param_uuid:
+		return name, re_uuid_pattern, nil
+	}
+	
+	//	Custom regex
+	re, err := regexp.Compile(param_type)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid regex parameter type: %s (%s): %v", slug, pattern, err)
+	}
+	if re.NumSubexp() != 0 {
+		return "", "", fmt.Errorf("Regex parameter type can not contain capture groups (use non-capturing groups): %s (%s)", slug, pattern)
+	}
+	return name, "("+param_type+")", nil
+}
+
+//	Sort rank of dynamic slugs: :file, typed parameters and untyped parameters
+func param_rank(slug string) int {
+	switch {
+	case slug == pattern_file:
+		return 0
+	case strings.HasSuffix(slug, ">"):
+		return 1
 	}
+	return 2
 }
 
-func validate_pattern(pattern string){
+func validate_pattern(pattern string) error {
 	if pattern == "" {
-		log.Fatal("Route cannot be empty")
+		return fmt.Errorf("Route cannot be empty")
 	}
 	if pattern[0] != '/' {
-		log.Fatalf("Route must start with '/': %s -> /%s", pattern, pattern)
+		return fmt.Errorf("Route must start with '/': %s -> /%s", pattern, pattern)
 	}
+	return nil
 }
 
 func timeout_min(timeout int) int {
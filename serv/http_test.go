@@ -1,12 +1,16 @@
 package serv
 
 import (
+	"os"
 	"fmt"
+	"log"
+	"bytes"
 	"slices"
 	"strings"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"github.com/clarkk/go-util/serv/req"
 )
 
 const (
@@ -260,6 +264,404 @@ func Test_priority_routing(t *testing.T){
 	}
 }
 
+func Test_route_params(t *testing.T){
+	tests := []struct{
+		name		string
+		url			string
+		want_code	int
+		want_body	string
+	}{
+		{"int", base_url+"/user/42/file/report", http.StatusOK, "user 42 report"},
+		{"int negative", base_url+"/user/-7/file/report", http.StatusOK, "user -7 report"},
+		{"int invalid", base_url+"/user/abc/file/report", http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+		{"uuid", base_url+"/uuid/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusOK, "uuid 6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"uuid invalid", base_url+"/uuid/6ba7b810", http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+		{"regex", base_url+"/code/DKK", http.StatusOK, "code DKK"},
+		{"regex invalid", base_url+"/code/dkk", http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+		{"mixed with anonymous", base_url+"/mixed/a/b", http.StatusOK, "mixed a b b"},
+	}
+	
+	h := NewHTTP(tld, "", 0)
+	
+	h.Subhost(sld).
+		Route_exact(GET, "/user/:id<int>/file/:name", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprintf(w, "user %d %s", Param_int(r, "id"), Param(r, "name"))
+		}).
+		Route_exact(GET, "/uuid/:id<uuid>", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprintf(w, "uuid %s", Param(r, "id"))
+		}).
+		Route_exact(GET, "/code/:code<[A-Z]{3}>", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprintf(w, "code %s", Param(r, "code"))
+		}).
+		Route_exact(GET, "/mixed/:slug/:name", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprintf(w, "mixed %s %s %s", Get_slug(r, 0), Get_slug(r, 1), Param(r, "name"))
+		})
+	
+	handler := h.test_handler()
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test_request(t, http.MethodGet, tt.url))
+			
+			code := w.Result().StatusCode
+			body := strings.TrimSpace(w.Body.String())
+			
+			if code != tt.want_code || body != tt.want_body {
+				t.Fatalf("want [%d] [%s] but got [%d] [%s]", tt.want_code, tt.want_body, code, body)
+			}
+		})
+	}
+}
+
+func Test_hosts(t *testing.T){
+	h := NewHTTP(tld, "", 0).
+		Add_TLD("staging.com")
+	
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, name+" "+Subhost_label(r))
+		}
+	}
+	
+	h.Apex().Route(GET, "/", 0, handler("apex"))
+	h.Subhost(sld).Route(GET, "/", 0, handler("subdomain"))
+	h.Subhost("*.").Route(GET, "/", 0, handler("wildcard"))
+	h.Subhost("*.tenants.").Route(GET, "/", 0, handler("tenant"))
+	
+	tests := []struct{
+		host		string
+		want_code	int
+		want_body	string
+	}{
+		{tld, http.StatusOK, "apex"},
+		{tld+":8000", http.StatusOK, "apex"},
+		{"staging.com", http.StatusOK, "apex"},
+		{base_url, http.StatusOK, "subdomain"},
+		{"SubDomain.Domain.com:443", http.StatusOK, "subdomain"},
+		{sld+"staging.com", http.StatusOK, "subdomain"},
+		{"customer."+tld, http.StatusOK, "wildcard customer"},
+		{"acme.tenants.staging.com", http.StatusOK, "tenant acme"},
+		{"a.b."+tld, http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+		{"unknown.com", http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+		{"x"+tld, http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+	}
+	
+	handler_serve := h.test_handler()
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T){
+			w := httptest.NewRecorder()
+			r := test_request(t, http.MethodGet, base_url+"/")
+			r.Host = tt.host
+			handler_serve.ServeHTTP(w, r)
+			
+			code := w.Result().StatusCode
+			body := strings.TrimSpace(w.Body.String())
+			if code != tt.want_code || body != tt.want_body {
+				t.Fatalf("want [%d] [%s] but got [%d] [%s]", tt.want_code, tt.want_body, code, body)
+			}
+		})
+	}
+}
+
+func Test_middleware(t *testing.T){
+	adapter := func(name string) Adapter {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request){
+				fmt.Fprintf(w, "%s(%s) ", name, Route_pattern(r))
+				next(w, r)
+			}
+		}
+	}
+	
+	h := NewHTTP(tld, "", 0)
+	h.Use(adapter("http1"), adapter("http2"))
+	
+	h.Subhost(sld).
+		Use(adapter("subhost")).
+		Route(GET, "/route/:id<int>", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, "handler")
+		}, adapter("route1"), adapter("route2")).
+		Route(GET, "/", 60, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, "handler")
+		})
+	
+	handler := h.test_handler()
+	
+	tests := []struct{
+		url			string
+		want_body	string
+	}{
+		{base_url+"/route/1", "http1(/route/:id<int>) http2(/route/:id<int>) subhost(/route/:id<int>) route1(/route/:id<int>) route2(/route/:id<int>) handler"},
+		{base_url+"/", "http1(/) http2(/) subhost(/) handler"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, test_request(t, http.MethodGet, tt.url))
+		
+		if body := w.Body.String(); body != tt.want_body {
+			t.Fatalf("HTTP response want [%s] but got [%s]", tt.want_body, body)
+		}
+	}
+}
+
+func Test_groups(t *testing.T){
+	adapter := func(name string) Adapter {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request){
+				fmt.Fprint(w, name+" ")
+				next(w, r)
+			}
+		}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request){
+		fmt.Fprint(w, Route_pattern(r))
+	}
+	
+	h := NewHTTP(tld, "", 0)
+	s := h.Subhost(sld).
+		Use(adapter("subhost"))
+	
+	api := s.Group("/api/").
+		Use(adapter("api")).
+		Timeout(30).
+		Route_exact(GET, "/", 0, handler)
+	
+	api.Group("/v2").
+		Use(adapter("v2")).
+		Route_exact(GET, "/user/:id<int>", 0, handler, adapter("route")).
+		Route(POST, "/upload", 120, handler)
+	
+	api.Route_blind(ALL, "/internal")
+	
+	tests := []struct{
+		method		string
+		url			string
+		want_code	int
+		want_body	string
+	}{
+		{http.MethodGet, base_url+"/api", http.StatusOK, "subhost api /api"},
+		{http.MethodGet, base_url+"/api/v2/user/7", http.StatusOK, "subhost api v2 route /api/v2/user/:id<int>"},
+		{http.MethodPost, base_url+"/api/v2/upload", http.StatusOK, "subhost api v2 /api/v2/upload"},
+		{http.MethodGet, base_url+"/api/internal", http.StatusNotFound, http.StatusText(http.StatusNotFound)},
+	}
+	
+	handler_serve := h.test_handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler_serve.ServeHTTP(w, test_request(t, tt.method, tt.url))
+		
+		code := w.Result().StatusCode
+		body := strings.TrimSpace(w.Body.String())
+		if code != tt.want_code || body != tt.want_body {
+			t.Fatalf("%s want [%d] [%s] but got [%d] [%s]", tt.url, tt.want_code, tt.want_body, code, body)
+		}
+	}
+	
+	//	All group routes are in the subhost route table with group default timeout
+	want_timeouts := map[string]int{
+		"/api":					30,
+		"/api/v2/user/:id<int>":	30,
+		"/api/v2/upload":		120,
+		"/api/internal":		0,
+	}
+	if len(s.routes) != len(want_timeouts) {
+		t.Fatalf("Routes want %d but got %d", len(want_timeouts), len(s.routes))
+	}
+	for _, route := range s.routes {
+		want, ok := want_timeouts[route.pattern]
+		if !ok {
+			t.Fatalf("Unexpected route: %s", route.pattern)
+		}
+		for _, handler := range route.methods {
+			if handler.timeout != want {
+				t.Fatalf("Route %s timeout want %d but got %d", route.pattern, want, handler.timeout)
+			}
+		}
+	}
+}
+
+func Test_errors(t *testing.T){
+	h := NewHTTP(tld, "", 0)
+	
+	h.Subhost("api.").
+		Errors_JSON().
+		Route(POST, "/post", 0, func(w http.ResponseWriter, r *http.Request){}).
+		Route(GET, "/panic", 0, func(w http.ResponseWriter, r *http.Request){
+			panic("test panic")
+		})
+	
+	h.Subhost(sld).
+		Errors(func(w http.ResponseWriter, r *http.Request, status int, err any){
+			w.WriteHeader(status)
+			fmt.Fprintf(w, "custom %d %v", status, err)
+		}).
+		Route(GET, "/panic", 0, func(w http.ResponseWriter, r *http.Request){
+			panic("test panic")
+		})
+	
+	tests := []struct{
+		method			string
+		url				string
+		want_code		int
+		want_type		string
+		want_body		string
+	}{
+		{http.MethodGet, "api."+tld+"/unknown", http.StatusNotFound, TYPE_PROBLEM_JSON, `{"type":"about:blank","title":"Not Found","status":404,"instance":"/unknown"}`},
+		{http.MethodGet, "api."+tld+"/post", http.StatusMethodNotAllowed, TYPE_PROBLEM_JSON, `{"type":"about:blank","title":"Method Not Allowed","status":405,"instance":"/post"}`},
+		{http.MethodGet, "api."+tld+"/panic", http.StatusInternalServerError, TYPE_PROBLEM_JSON, `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/panic"}`},
+		{http.MethodGet, base_url+"/unknown", http.StatusNotFound, "", "custom 404 <nil>"},
+		{http.MethodGet, base_url+"/panic", http.StatusInternalServerError, "", "custom 500 test panic"},
+		{http.MethodGet, "unknown."+tld+"/", http.StatusNotFound, "text/plain; charset=utf-8", http.StatusText(http.StatusNotFound)},
+	}
+	
+	handler := h.test_handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, test_request(t, tt.method, tt.url))
+		
+		code := w.Result().StatusCode
+		body := strings.TrimSpace(w.Body.String())
+		if code != tt.want_code || body != tt.want_body {
+			t.Fatalf("%s want [%d] [%s] but got [%d] [%s]", tt.url, tt.want_code, tt.want_body, code, body)
+		}
+		if tt.want_type != "" {
+			if content_type := w.Header().Get("Content-Type"); content_type != tt.want_type {
+				t.Fatalf("%s Content-Type want [%s] but got [%s]", tt.url, tt.want_type, content_type)
+			}
+		}
+	}
+}
+
+func Test_methods(t *testing.T){
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Route(GET, "/user", 0, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("X-Method", r.Method)
+			fmt.Fprint(w, "user")
+		}).
+		Route(PUT, "/user", 0, func(w http.ResponseWriter, r *http.Request){}).
+		Route(PATCH, "/user", 0, func(w http.ResponseWriter, r *http.Request){}).
+		Route(OPTIONS, "/custom", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, "custom")
+		}).
+		Route(POST, "/post", 0, func(w http.ResponseWriter, r *http.Request){}).
+		Route_blind(GET, "/blind")
+	
+	handler := h.test_handler()
+	
+	tests := []struct{
+		method		string
+		path		string
+		want_status	int
+		want_allow	string
+		want_body	string
+	}{
+		{http.MethodHead, "/user", http.StatusOK, "", ""},
+		{http.MethodPut, "/user", http.StatusOK, "", ""},
+		{http.MethodPatch, "/user", http.StatusOK, "", ""},
+		{http.MethodOptions, "/user", http.StatusNoContent, "GET, HEAD, OPTIONS, PATCH, PUT", ""},
+		{http.MethodDelete, "/user", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, PATCH, PUT", ""},
+		{http.MethodOptions, "/custom", http.StatusOK, "", "custom"},
+		{http.MethodHead, "/post", http.StatusMethodNotAllowed, "OPTIONS, POST", ""},
+		{http.MethodOptions, "/blind", http.StatusNotFound, "", "Not Found\n"},
+		{http.MethodHead, "/blind", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T){
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test_request(t, tt.method, base_url+tt.path))
+			
+			if w.Code != tt.want_status {
+				t.Fatalf("Status want %d but got %d", tt.want_status, w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.want_allow {
+				t.Fatalf("Allow want [%s] but got [%s]", tt.want_allow, got)
+			}
+			if tt.want_status != http.StatusMethodNotAllowed && w.Body.String() != tt.want_body {
+				t.Fatalf("Body want [%s] but got [%s]", tt.want_body, w.Body.String())
+			}
+		})
+	}
+	
+	//	HEAD is answered by the GET handler
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, test_request(t, http.MethodHead, base_url+"/user"))
+	if got := w.Header().Get("X-Method"); got != http.MethodHead {
+		t.Fatalf("HEAD handler want X-Method HEAD but got [%s]", got)
+	}
+}
+
+func Test_request_ID(t *testing.T){
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Route(GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, req.Request_ID(r))
+		})
+	
+	handler := h.test_handler()
+	
+	tests := []struct{
+		name		string
+		header		string
+		want_echo	bool
+	}{
+		{"accept", "abc-123.def:ghi_jkl", true},
+		{"generate", "", false},
+		{"invalid", "abc\ninjected", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			r := test_request(t, http.MethodGet, base_url+"/")
+			if tt.header != "" {
+				r.Header.Set(req.REQUEST_ID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			
+			id := w.Header().Get(req.REQUEST_ID)
+			if id == "" || id != w.Body.String() {
+				t.Fatalf("Request ID header [%s] and context [%s] mismatch", id, w.Body.String())
+			}
+			if (id == tt.header) != tt.want_echo {
+				t.Fatalf("Request ID [%s] from header [%s]", id, tt.header)
+			}
+		})
+	}
+}
+
+func Test_recover(t *testing.T){
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	
+	r := test_request(t, http.MethodGet, base_url+"/")
+	r = r.WithContext(req.With_request_ID(r.Context(), "abc-123"))
+	w := httptest.NewRecorder()
+	func(){
+		defer Recover_request(NewWriter(w), r)
+		panic("boom")
+	}()
+	
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Status want 500 but got %d", w.Code)
+	}
+	if !strings.Contains(buf.String(), "Request ID abc-123: ") || !strings.Contains(buf.String(), "boom") {
+		t.Fatalf("Log want request ID and panic but got: %s", buf.String())
+	}
+	
+	buf.Reset()
+	w = httptest.NewRecorder()
+	func(){
+		defer Recover(NewWriter(w))
+		panic("boom")
+	}()
+	if w.Code != http.StatusInternalServerError || !strings.Contains(buf.String(), "boom") {
+		t.Fatalf("Recover want 500 and logged panic but got %d: %s", w.Code, buf.String())
+	}
+}
+
 func (h *HTTP) test_handler() http.HandlerFunc {
 	return http.HandlerFunc(h.serve)
 }
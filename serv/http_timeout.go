@@ -0,0 +1,209 @@
+package serv
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"github.com/go-errors/errors"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+/*
+	Handlers with timeout are served in a goroutine with a timeout writer.
+	Output is buffered until the handler returns or flushes, so the timeout response (HTTP 408)
+	is never mixed with handler output. After the timeout, writes return http.ErrHandlerTimeout.
+*/
+
+type timeout_writer struct {
+	w			http.ResponseWriter
+	lock		sync.Mutex
+	header		http.Header
+	buf			bytes.Buffer
+	status		int
+	committed	bool
+	timed_out	bool
+}
+
+func newTimeout_writer(w http.ResponseWriter) *timeout_writer {
+	return &timeout_writer{
+		w:		w,
+		header:	w.Header().Clone(),
+	}
+}
+
+/*
+	Serve handler with timeout and carry panics back to the serving goroutine
+	
+	Writes racing the deadline (before the timeout response is sent) are buffered and discarded with the buffer
+	instead of returning http.ErrHandlerTimeout. Only writes after the timeout response is sent are rejected
+*/
+func (s *Subhost) serve_timeout(handler http.HandlerFunc, w http.ResponseWriter, r *http.Request, timeout time.Duration) (timed_out bool){
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+	
+	tw		:= newTimeout_writer(w)
+	done	:= make(chan struct{})
+	panics	:= make(chan *errors.Error, 1)
+	
+	go func(){
+		defer func(){
+			if err := recover(); err != nil {
+				tw.panic(r, errors.Wrap(err, 2), panics)
+			}
+		}()
+		
+		//	Serve HTTP request to client
+		handler(NewWriter(tw), r)
+		close(done)
+	}()
+	
+	//	Wait until the handler returns, panics or the context is done/canceled/timeout
+	select {
+	case <-done:
+		tw.finish()
+		return false
+	case err := <-panics:
+		panic(err)
+	case <-ctx.Done():
+	}
+	
+	committed := tw.timeout()
+	
+	//	Handler panicked before the timeout
+	select {
+	case err := <-panics:
+		panic(err)
+	default:
+	}
+	
+	//	Return HTTP 408 Timeout if request reached timeout and no output is sent
+	if ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	if !committed {
+		s.error(w, r, http.StatusRequestTimeout, ctx.Err())
+	}
+	return true
+}
+
+func (tw *timeout_writer) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeout_writer) WriteHeader(status int){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	
+	if tw.timed_out || tw.status != 0 {
+		return
+	}
+	tw.status = status
+	if tw.committed {
+		tw.w.WriteHeader(status)
+	}
+}
+
+func (tw *timeout_writer) Write(b []byte) (int, error){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	
+	if tw.timed_out {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	if tw.committed {
+		return tw.w.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+//	Send buffered output and stream the rest of the response (the timeout response can no longer be sent)
+func (tw *timeout_writer) Flush(){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	
+	if tw.timed_out {
+		return
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.commit()
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *timeout_writer) Hijack() (net.Conn, *bufio.ReadWriter, error){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	
+	if tw.timed_out {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	h, ok := tw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Underlying ResponseWriter does not support hijacking")
+	}
+	tw.committed = true
+	return h.Hijack()
+}
+
+func (tw *timeout_writer) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+//	Send buffered output when the handler returns
+func (tw *timeout_writer) finish(){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	tw.commit()
+}
+
+//	Block output after timeout and check if output is already sent
+func (tw *timeout_writer) timeout() bool {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	tw.timed_out = true
+	return tw.committed
+}
+
+//	Carry panic to the serving goroutine or log it if the timeout response is already sent
+func (tw *timeout_writer) panic(r *http.Request, err *errors.Error, panics chan<- *errors.Error){
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+	if tw.timed_out {
+		log.Printf("Request ID %s (after timeout): %s", req.Request_ID(r), err.ErrorStack())
+		return
+	}
+	panics <- err
+}
+
+//	Write headers and buffered output to the response (must hold lock)
+func (tw *timeout_writer) commit(){
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+	
+	header := tw.w.Header()
+	clear(header)
+	for key, values := range tw.header {
+		header[key] = values
+	}
+	if tw.status != 0 {
+		tw.w.WriteHeader(tw.status)
+	}
+	if tw.buf.Len() > 0 {
+		tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
\ No newline at end of file
@@ -0,0 +1,116 @@
+package serv
+
+import (
+	"io"
+	"time"
+	"errors"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+/*
+	Test
+	# go test . -race -run Test_timeout -v
+*/
+
+func Test_timeout(t *testing.T){
+	write_err := make(chan error, 1)
+	release := make(chan struct{})
+	timed_out := make(chan struct{})
+	
+	h := NewHTTP(tld, "", 0)
+	h.Subhost(sld).
+		Route(GET, "/fast", 1, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("X-Test", "fast")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, "fast")
+		}).
+		Route(GET, "/slow", 1, func(w http.ResponseWriter, r *http.Request){
+			w.Header().Set("X-Test", "slow")
+			io.WriteString(w, "partial")
+			//	Write after the timeout response is sent
+			<-timed_out
+			_, err := io.WriteString(w, "late")
+			write_err <- err
+		}).
+		Route(GET, "/stream", 1, func(w http.ResponseWriter, r *http.Request){
+			io.WriteString(w, "streamed")
+			w.(http.Flusher).Flush()
+			<-release
+		}).
+		Route(GET, "/panic", 1, func(w http.ResponseWriter, r *http.Request){
+			io.WriteString(w, "partial")
+			panic("test panic")
+		}).
+		Route(GET, "/panic-late", 1, func(w http.ResponseWriter, r *http.Request){
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			panic("late panic")
+		})
+	
+	request := func(path string) (*httptest.ResponseRecorder, time.Duration){
+		start := time.Now()
+		w := httptest.NewRecorder()
+		h.test_handler().ServeHTTP(w, test_request(t, http.MethodGet, base_url+path))
+		return w, time.Since(start)
+	}
+	
+	t.Run("fast", func(t *testing.T){
+		t.Parallel()
+		w, _ := request("/fast")
+		if w.Code != http.StatusCreated || w.Body.String() != "fast" {
+			t.Fatalf("want [201] [fast] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		test_header(t, w, "X-Test", "fast")
+		if w.Header().Get("X-Request-ID") == "" {
+			t.Fatal("Request ID header missing")
+		}
+	})
+	
+	t.Run("slow", func(t *testing.T){
+		t.Parallel()
+		w, _ := request("/slow")
+		close(timed_out)
+		if w.Code != http.StatusRequestTimeout || w.Body.String() != "Request Timeout\n" {
+			t.Fatalf("want [408] [Request Timeout] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		test_header(t, w, "X-Test", "")
+		if err := <-write_err; !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Fatalf("Write after timeout want http.ErrHandlerTimeout but got %v", err)
+		}
+	})
+	
+	t.Run("stream", func(t *testing.T){
+		t.Parallel()
+		w, elapsed := request("/stream")
+		close(release)
+		if elapsed < time.Second {
+			t.Fatalf("Stream want served until timeout but took %s", elapsed)
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "streamed" || !w.Flushed {
+			t.Fatalf("want flushed [200] [streamed] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+	})
+	
+	t.Run("panic", func(t *testing.T){
+		t.Parallel()
+		w, elapsed := request("/panic")
+		if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error\n" {
+			t.Fatalf("want [500] [Internal Server Error] but got [%d] [%s]", w.Code, w.Body.String())
+		}
+		if elapsed >= time.Second {
+			t.Fatalf("Panic want served before timeout but took %s", elapsed)
+		}
+	})
+	
+	t.Run("panic after timeout", func(t *testing.T){
+		t.Parallel()
+		w, _ := request("/panic-late")
+		if w.Code != http.StatusRequestTimeout {
+			t.Fatalf("want [408] but got [%d]", w.Code)
+		}
+		//	Panic is logged by the handler goroutine
+		time.Sleep(50 * time.Millisecond)
+	})
+}
\ No newline at end of file
@@ -0,0 +1,172 @@
+package serv
+
+import (
+	"os"
+	"fmt"
+	"log"
+	"time"
+	"errors"
+	"syscall"
+	"os/signal"
+	"sync/atomic"
+	"crypto/tls"
+	"github.com/clarkk/go-util/cmd"
+)
+
+const tls_poll_interval = 10 * time.Second
+
+type tls_cert struct {
+	cert_file	string
+	key_file	string
+	mod_time	time.Time
+	cert		atomic.Pointer[tls.Certificate]
+}
+
+//	Serve HTTPS with default certificate (used when no subhost certificate matches SNI)
+func (h *HTTP) TLS(cert_file, key_file string) *HTTP {
+	c, err := newTLS_cert(cert_file, key_file)
+	if err != nil {
+		h.add_error(err)
+		return h
+	}
+	h.tls = c
+	return h
+}
+
+//	Serve HTTPS with subhost certificate picked via SNI
+func (s *Subhost) TLS(cert_file, key_file string) *Subhost {
+	c, err := newTLS_cert(cert_file, key_file)
+	if err != nil {
+		s.add_error(err)
+		return s
+	}
+	s.tls = c
+	return s
+}
+
+func newTLS_cert(cert_file, key_file string) (*tls_cert, error){
+	if cert_file == "" || key_file == "" {
+		return nil, fmt.Errorf("TLS certificate and key files are required")
+	}
+	return &tls_cert{
+		cert_file:	cert_file,
+		key_file:	key_file,
+	}, nil
+}
+
+//	Check if any certificate is applied to server or subhosts
+func (h *HTTP) tls_enabled() bool {
+	return len(h.tls_certs()) > 0
+}
+
+func (h *HTTP) tls_config() *tls.Config {
+	return &tls.Config{
+		MinVersion:		tls.VersionTLS12,
+		GetCertificate:	h.get_certificate,
+	}
+}
+
+//	Pick certificate via SNI
+func (h *HTTP) get_certificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error){
+	if s, _, err := h.resolve_subhost(hello.ServerName); err == nil && s.tls != nil {
+		return s.tls.cert.Load(), nil
+	}
+	if h.tls != nil {
+		return h.tls.cert.Load(), nil
+	}
+	return nil, fmt.Errorf("No TLS certificate for server name: %s", hello.ServerName)
+}
+
+//	Load all certificates before serving
+func (h *HTTP) load_tls() error {
+	var errs []error
+	for _, c := range h.tls_certs() {
+		if _, err := c.reload(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//	Reload certificates on SIGHUP or when certificate files are changed
+func (h *HTTP) watch_tls(quit <-chan struct{}){
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	
+	ticker := time.NewTicker(tls_poll_interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-quit:
+			return
+		case <-hup:
+			cmd.Out("HTTP server received SIGHUP to reload TLS certificates")
+			h.reload_tls(true)
+		case <-ticker.C:
+			h.reload_tls(false)
+		}
+	}
+}
+
+//	Swap certificates without dropping connections (the previous certificate is kept if loading fails)
+func (h *HTTP) reload_tls(force bool){
+	for _, c := range h.tls_certs() {
+		reloaded, err := c.reload(force)
+		if err != nil {
+			log.Printf("TLS certificate reload: %v", err)
+			continue
+		}
+		if reloaded {
+			cmd.Out("TLS certificate reloaded: "+c.cert_file)
+		}
+	}
+}
+
+func (h *HTTP) tls_certs() []*tls_cert {
+	var certs []*tls_cert
+	if h.tls != nil {
+		certs = append(certs, h.tls)
+	}
+	for _, s := range h.subhosts {
+		if s.tls != nil {
+			certs = append(certs, s.tls)
+		}
+	}
+	return certs
+}
+
+//	Reload certificate if forced or the files have been modified
+func (c *tls_cert) reload(force bool) (bool, error){
+	mod_time, err := c.files_mod_time()
+	if err != nil {
+		return false, err
+	}
+	if !force && mod_time.Equal(c.mod_time) {
+		return false, nil
+	}
+	
+	cert, err := tls.LoadX509KeyPair(c.cert_file, c.key_file)
+	if err != nil {
+		return false, fmt.Errorf("Unable to load TLS certificate %s: %w", c.cert_file, err)
+	}
+	c.cert.Store(&cert)
+	c.mod_time = mod_time
+	return true, nil
+}
+
+//	Latest modification time of certificate and key file
+func (c *tls_cert) files_mod_time() (time.Time, error){
+	var mod_time time.Time
+	for _, file := range []string{c.cert_file, c.key_file} {
+		finfo, err := os.Stat(file)
+		if err != nil {
+			return mod_time, fmt.Errorf("Unable to stat TLS file %s: %w", file, err)
+		}
+		if finfo.ModTime().After(mod_time) {
+			mod_time = finfo.ModTime()
+		}
+	}
+	return mod_time, nil
+}
\ No newline at end of file
@@ -0,0 +1,188 @@
+package serv
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"testing"
+	"math/big"
+	"net"
+	"net/http"
+	"io"
+	"context"
+	"crypto/tls"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"path/filepath"
+)
+
+func Test_TLS(t *testing.T){
+	dir := t.TempDir()
+	
+	default_cert, default_key 	:= test_write_cert(t, dir, "default", "default")
+	secure_cert, secure_key 	:= test_write_cert(t, dir, "secure", "secure")
+	
+	h := NewHTTP(tld, "", 0).
+		TLS(default_cert, default_key)
+	
+	h.Subhost(sld).
+		Route(GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, "subdomain")
+		})
+	
+	h.Subhost("secure.").
+		TLS(secure_cert, secure_key).
+		Route(GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			fmt.Fprint(w, "secure")
+		})
+	
+	if err := h.load_tls(); err != nil {
+		t.Fatal(err)
+	}
+	
+	t.Run("SNI", func(t *testing.T){
+		tests := []struct{
+			server_name	string
+			want		string
+		}{
+			{"secure."+tld, "secure"},
+			{"SECURE."+tld, "secure"},
+			{base_url, "default"},
+			{"unknown.com", "default"},
+			{"", "default"},
+		}
+		for _, tt := range tests {
+			if got := test_cert_name(t, h, tt.server_name); got != tt.want {
+				t.Fatalf("Certificate for %q want [%s] but got [%s]", tt.server_name, tt.want, got)
+			}
+		}
+	})
+	
+	t.Run("serve HTTPS", func(t *testing.T){
+		ln, err := tls.Listen("tcp", "127.0.0.1:0", h.tls_config())
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := &http.Server{
+			Handler: h.test_handler(),
+		}
+		go srv.Serve(ln)
+		defer srv.Close()
+		
+		for _, host := range []string{"secure."+tld, base_url} {
+			client := test_tls_client(ln.Addr().String(), host)
+			res, err := client.Get("https://"+host+"/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			
+			subject := res.TLS.PeerCertificates[0].Subject.CommonName
+			if want := map[string]string{"secure."+tld: "secure", base_url: "default"}[host]; subject != want {
+				t.Fatalf("Certificate for %s want [%s] but got [%s]", host, want, subject)
+			}
+			if want := map[string]string{"secure."+tld: "secure", base_url: "subdomain"}[host]; string(b) != want {
+				t.Fatalf("HTTP response want [%s] but got [%s]", want, b)
+			}
+		}
+	})
+	
+	t.Run("reload on file change", func(t *testing.T){
+		//	No reload when files are unchanged
+		h.reload_tls(false)
+		if got := test_cert_name(t, h, "secure."+tld); got != "secure" {
+			t.Fatalf("Certificate want [secure] but got [%s]", got)
+		}
+		
+		//	Replace certificate files with a future modification time
+		test_write_cert(t, dir, "secure", "renewed")
+		future := time.Now().Add(time.Minute)
+		for _, file := range []string{secure_cert, secure_key} {
+			if err := os.Chtimes(file, future, future); err != nil {
+				t.Fatal(err)
+			}
+		}
+		
+		h.reload_tls(false)
+		if got := test_cert_name(t, h, "secure."+tld); got != "renewed" {
+			t.Fatalf("Certificate want [renewed] but got [%s]", got)
+		}
+	})
+	
+	t.Run("keep certificate on invalid reload", func(t *testing.T){
+		if err := os.WriteFile(secure_cert, []byte("invalid"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		h.reload_tls(true)
+		if got := test_cert_name(t, h, "secure."+tld); got != "renewed" {
+			t.Fatalf("Certificate want [renewed] but got [%s]", got)
+		}
+	})
+}
+
+func test_cert_name(t *testing.T, h *HTTP, server_name string) string {
+	cert, err := h.get_certificate(&tls.ClientHelloInfo{
+		ServerName: server_name,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+//	Client that dials the test listener for any host and accepts self-signed certificates
+func test_tls_client(addr, server_name string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error){
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+			TLSClientConfig: &tls.Config{
+				ServerName:			server_name,
+				InsecureSkipVerify:	true,
+			},
+		},
+	}
+}
+
+//	Generate self-signed certificate with common name
+func test_write_cert(t *testing.T, dir, name, common_name string) (string, string){
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:	big.NewInt(time.Now().UnixNano()),
+		Subject:		pkix.Name{CommonName: common_name},
+		NotBefore:		time.Now().Add(-time.Hour),
+		NotAfter:		time.Now().Add(time.Hour),
+		DNSNames:		[]string{common_name},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key_der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	cert_file	:= filepath.Join(dir, name+".crt")
+	key_file	:= filepath.Join(dir, name+".key")
+	if err := os.WriteFile(cert_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(key_file, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key_der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cert_file, key_file
+}
\ No newline at end of file
@@ -0,0 +1,142 @@
+package serv
+
+import (
+	"fmt"
+	"errors"
+	"slices"
+	"regexp"
+)
+
+type Route_info struct {
+	Subhost		string		`json:"subhost"`
+	Method		string		`json:"method"`
+	Pattern		string		`json:"pattern"`
+	Exact		bool		`json:"exact"`
+	Blind		bool		`json:"blind"`
+	Timeout		int			`json:"timeout"`
+	Params		[]string	`json:"params,omitempty"`
+}
+
+//	Validate registration of subhosts and routes and detect unreachable routes (shadowed by preceding routes)
+func (h *HTTP) Validate() error {
+	errs := slices.Clone(h.errs)
+	for _, s := range h.detached {
+		errs = append(errs, s.errs...)
+	}
+	for _, sld := range h.sorted_subhosts() {
+		s := h.subhosts[sld]
+		errs = append(errs, s.errs...)
+		
+		//	Validate a sorted copy without changing the routes being served
+		routes := s.routes
+		if s.priority_routing {
+			routes = slices.Clone(routes)
+			slices.SortFunc(routes, compare_priority)
+		}
+		for i, r := range routes {
+			for _, prev := range routes[:i] {
+				if prev.shadows(r) {
+					errs = append(errs, s.wrap_error(fmt.Errorf("Route is unreachable: %s is shadowed by %s", r.describe(), prev.describe())))
+					break
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//	Get route table in the order routes are matched
+func (h *HTTP) Routes() []Route_info {
+	var list []Route_info
+	for _, sld := range h.sorted_subhosts() {
+		for _, r := range h.subhosts[sld].routes {
+			methods := make([]string, 0, len(r.methods))
+			for method := range r.methods {
+				methods = append(methods, method)
+			}
+			slices.Sort(methods)
+			
+			var params []string
+			if slices.ContainsFunc(r.params, func(name string) bool { return name != "" }) {
+				params = r.params
+			}
+			for _, method := range methods {
+				handler := r.methods[method]
+				list = append(list, Route_info{
+					Subhost:	sld,
+					Method:		method,
+					Pattern:	r.pattern,
+					Exact:		r.exact,
+					Blind:		handler.blind,
+					Timeout:	handler.timeout,
+					Params:		params,
+				})
+			}
+		}
+	}
+	return list
+}
+
+func (h *HTTP) sorted_subhosts() []string {
+	list := make([]string, 0, len(h.subhosts))
+	for sld := range h.subhosts {
+		list = append(list, sld)
+	}
+	slices.Sort(list)
+	return list
+}
+
+//	Check if route matches every path matched by the other route (only reported when certain)
+func (r *route) shadows(other *route) bool {
+	if r.exact {
+		if !other.exact || len(r.slugs) != len(other.slugs) {
+			return false
+		}
+	} else if len(r.slugs) > len(other.slugs) {
+		return false
+	}
+	
+	for i, slug := range r.slugs {
+		if !r.slug_covers(i, slug, other, other.slugs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//	Check if slug matches every value matched by the other slug
+func (r *route) slug_covers(i int, slug string, other *route, other_slug string) bool {
+	if slug == other_slug && slug[0] != ':' {
+		return true
+	}
+	if slug[0] != ':' {
+		return false
+	}
+	
+	re := r.slug_regex(i)
+	if re == re_slug_pattern {
+		return true
+	}
+	if other_slug[0] == ':' {
+		return re == other.slug_regex(i)
+	}
+	return regexp.MustCompile("^"+re+"$").MatchString(other_slug)
+}
+
+//	Get regex of dynamic slug
+func (r *route) slug_regex(i int) string {
+	var param int
+	for _, slug := range r.slugs[:i] {
+		if slug[0] == ':' {
+			param++
+		}
+	}
+	return r.param_regex[param]
+}
+
+func (r *route) describe() string {
+	if r.exact {
+		return r.pattern+" (exact)"
+	}
+	return r.pattern
+}
\ No newline at end of file
@@ -0,0 +1,140 @@
+package serv
+
+import (
+	"strings"
+	"testing"
+	"net/http"
+)
+
+func Test_validate(t *testing.T){
+	handler := func(w http.ResponseWriter, r *http.Request){}
+	
+	t.Run("registration errors", func(t *testing.T){
+		h := NewHTTP(tld, "", 0).
+			Add_TLD(".invalid").
+			TLS("", "")
+		h.Subhost("invalid").
+			Route(GET, "bad", 0, handler)
+		h.Subhost(sld)
+		h.Subhost(sld).
+			Route(GET, "/duplicate", 0, handler).
+			Route(GET, "on-duplicate", 0, handler)
+		h.Subhost_path_prefix("prefix.", "no-slash")
+		h.Subhost("api.").
+			Route(GET, "no-slash", 0, handler).
+			Route(GET, "/user", 0, handler).
+			Route(GET, "/user", 0, handler).
+			Route(GET, "/user/:id<int>/:id", 0, handler).
+			Route(GET, "/bad/:id<(\\d+)>", 0, handler).
+			Route_exact(POST, "/user", 0, handler).
+			CORS(CORS{}).
+			Group("/").
+				Route(GET, "/in-root-group", 0, handler)
+		
+		err := h.Validate()
+		if err == nil {
+			t.Fatal("Validate want errors")
+		}
+		for _, want := range []string{
+			"Invalid TLD: .invalid",
+			"TLS certificate and key files are required",
+			"Subhost must end with '.': invalid -> invalid.",
+			"Subhost already exists: subdomain.",
+			"Subhost invalid: Route must start with '/': bad -> /bad",
+			"Subhost subdomain.: Route must start with '/': on-duplicate -> /on-duplicate",
+			"Path prefix must start with '/': no-slash -> /no-slash",
+			"Subhost api.: Route must start with '/': no-slash -> /no-slash",
+			"Subhost api.: Route is duplicate: GET /user",
+			"Subhost api.: Route parameter is duplicate: id (/user/:id<int>/:id)",
+			"Subhost api.: Regex parameter type can not contain capture groups",
+			"Subhost api.: Routes with exact/prefix can not be mixed: /user",
+			"Subhost api.: CORS requires allowed origins",
+			"Subhost api.: Group prefix can not be the root path",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("Validate error missing [%s] in:\n%s", want, err)
+			}
+		}
+		
+		//	Valid routes are still applied
+		if len(h.Routes()) != 2 {
+			t.Fatalf("Routes want 2 but got %d: %v", len(h.Routes()), h.Routes())
+		}
+	})
+	
+	t.Run("unreachable routes", func(t *testing.T){
+		h := NewHTTP(tld, "", 0)
+		h.Subhost(sld).
+			Route(GET, "/user", 0, handler).
+			Route_exact(GET, "/user/profile", 0, handler).
+			Route(GET, "/file/:name", 0, handler).
+			Route(POST, "/file/:id<int>/meta", 0, handler).
+			Route_exact(GET, "/id/:id<int>", 0, handler).
+			Route_exact(GET, "/id/42", 0, handler).
+			Route_exact(GET, "/id/abc", 0, handler).
+			Route_exact(GET, "/uuid/:id<uuid>", 0, handler).
+			Route_exact(GET, "/uuid/:key<int>", 0, handler).
+			Route(GET, "/", 0, handler).
+			Route(DELETE, "/last", 0, handler)
+		
+		err := h.Validate()
+		if err == nil {
+			t.Fatal("Validate want errors")
+		}
+		
+		want := []string{
+			"Subhost subdomain.: Route is unreachable: /user/profile (exact) is shadowed by /user",
+			"Subhost subdomain.: Route is unreachable: /file/:id<int>/meta is shadowed by /file/:name",
+			"Subhost subdomain.: Route is unreachable: /id/42 (exact) is shadowed by /id/:id<int> (exact)",
+			"Subhost subdomain.: Route is unreachable: /last is shadowed by /",
+		}
+		if got := strings.Split(err.Error(), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("Validate errors want:\n%s\ngot:\n%s", strings.Join(want, "\n"), err)
+		}
+	})
+	
+	t.Run("priority routing", func(t *testing.T){
+		h := NewHTTP(tld, "", 0)
+		h.Subhost(sld).
+			Priority_routing().
+			Route(GET, "/", 0, handler).
+			Route(GET, "/user", 0, handler).
+			Route_exact(GET, "/user/:id<int>", 0, handler).
+			Route_exact(GET, "/user/profile", 0, handler)
+		
+		if err := h.Validate(); err != nil {
+			t.Fatalf("Validate want no errors but got: %s", err)
+		}
+		//	Routes are sorted when the server runs
+		if r := h.subhosts[sld].routes[0]; r.pattern != "/" {
+			t.Fatalf("Validate want routes unchanged but first route is %s", r.pattern)
+		}
+	})
+}
+
+func Test_routes_export(t *testing.T){
+	handler := func(w http.ResponseWriter, r *http.Request){}
+	
+	h := NewHTTP(tld, "", 0)
+	h.Subhost("api.").
+		Route(POST, "/user/:id<int>", 30, handler).
+		Route(GET, "/user/:id<int>", 0, handler)
+	h.Subhost(sld).
+		Route_blind(ALL, "/hidden")
+	
+	want := []Route_info{
+		{Subhost: "api.", Method: "GET", Pattern: "/user/:id<int>", Timeout: 0, Params: []string{"id"}},
+		{Subhost: "api.", Method: "POST", Pattern: "/user/:id<int>", Timeout: 30, Params: []string{"id"}},
+		{Subhost: sld, Method: "*", Pattern: "/hidden", Blind: true},
+	}
+	got := h.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("Routes want %d but got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Subhost != want[i].Subhost || got[i].Method != want[i].Method || got[i].Pattern != want[i].Pattern ||
+			got[i].Blind != want[i].Blind || got[i].Timeout != want[i].Timeout || strings.Join(got[i].Params, ",") != strings.Join(want[i].Params, ",") {
+			t.Fatalf("Route %d want %+v but got %+v", i, want[i], got[i])
+		}
+	}
+}
\ No newline at end of file
@@ -12,6 +12,7 @@ type Writer struct {
 	sent_header		bool
 	status			int
 	bytes_sent		int
+	discard_body	bool
 }
 
 func NewWriter(w http.ResponseWriter) *Writer {
@@ -34,6 +35,9 @@ func (w *Writer) Write(b []byte) (int, error){
 	if !w.sent_header {
 		w.WriteHeader(http.StatusOK)
 	}
+	if w.discard_body {
+		return len(b), nil
+	}
 	n, err := w.ResponseWriter.Write(b)
 	w.bytes_sent += n
 	return n, err
@@ -54,6 +58,10 @@ func (w *Writer) Sent_header() bool {
 //	Flush implements the http.Flusher interface.
 //	This is critical for 2026 streaming APIs and Server-Sent Events (SSE).
 func (w *Writer) Flush() {
+	//	Flushing sends the header with the implicit status
+	if !w.sent_header {
+		w.WriteHeader(http.StatusOK)
+	}
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
@@ -0,0 +1,96 @@
+package limit
+
+import (
+	"math"
+	"time"
+)
+
+/*
+	Limit state is kept in milliseconds so the memory store and the Redis scripts share the same math
+*/
+
+type state struct {
+	//	Token bucket
+	tokens		float64
+	updated		int64
+	//	Sliding window
+	start		int64
+	prev		int64
+	curr		int64
+	//	State can be dropped when the limit is fully reset
+	expires		int64
+}
+
+//	Refill tokens since last request and take a token if available
+func (s *state) take_token_bucket(now int64, limit int, window int64) Result {
+	rate := float64(limit) / float64(window)
+	
+	if s.updated == 0 {
+		s.tokens = float64(limit)
+	} else if now > s.updated {
+		s.tokens = math.Min(float64(limit), s.tokens + float64(now - s.updated) * rate)
+	}
+	s.updated = now
+	
+	res := Result{
+		Limit: limit,
+	}
+	if s.tokens >= 1 {
+		s.tokens--
+		res.Allowed = true
+	} else {
+		res.Retry_after = duration((1 - s.tokens) / rate)
+	}
+	
+	reset := (float64(limit) - s.tokens) / rate
+	res.Remaining	= int(s.tokens)
+	res.Reset		= duration(reset)
+	s.expires		= now + int64(math.Ceil(reset))
+	return res
+}
+
+//	Count request in current window weighted with the previous window
+func (s *state) take_sliding_window(now int64, limit int, window int64) Result {
+	start := now - now % window
+	switch s.start {
+	case start:
+	case start - window:
+		s.prev, s.curr = s.curr, 0
+	default:
+		s.prev, s.curr = 0, 0
+	}
+	s.start = start
+	
+	elapsed		:= now - start
+	estimate	:= float64(s.prev) * float64(window - elapsed) / float64(window) + float64(s.curr)
+	
+	res := Result{
+		Limit: limit,
+	}
+	if estimate + 1 <= float64(limit) {
+		s.curr++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.Retry_after = duration(sliding_window_retry(s.prev, s.curr, limit, window, elapsed))
+	}
+	
+	res.Remaining	= max(int(float64(limit) - estimate), 0)
+	res.Reset		= duration(float64(window - elapsed))
+	s.expires		= start + 2 * window
+	return res
+}
+
+//	Time until the weighted count allows another request
+func sliding_window_retry(prev, curr int64, limit int, window, elapsed int64) float64 {
+	remaining := float64(window - elapsed)
+	if curr < int64(limit) && prev > 0 {
+		return math.Max(remaining - float64(int64(limit) - curr - 1) * float64(window) / float64(prev), 0)
+	}
+	//	Wait for the current window to weigh less in the next window
+	return remaining + float64(window) * (1 - float64(limit - 1) / float64(curr))
+}
+
+func duration(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
\ No newline at end of file
@@ -0,0 +1,39 @@
+package limit
+
+import (
+	"net/http"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/clarkk/go-util/sess"
+	"github.com/clarkk/go-util/serv"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+//	Get client key from request (an empty key is not limited)
+type Key func(r *http.Request) string
+
+//	Key by client IP
+func Key_IP(r *http.Request) string {
+	return "ip:"+req.Get_client_IP(r)
+}
+
+//	Key by session ID if a session is started by a preceding adapter, otherwise by client IP
+func Key_session(r *http.Request) string {
+	if s := sess.Request(r); s != nil && !s.Closed() {
+		//	Session IDs are hashed to keep them out of the store
+		hash := sha256.Sum256([]byte(s.Sid()))
+		return "sess:"+hex.EncodeToString(hash[:16])
+	}
+	return Key_IP(r)
+}
+
+//	Key by route pattern and client key to limit each route separately
+func Key_route(key Key) Key {
+	return func(r *http.Request) string {
+		k := key(r)
+		if k == "" {
+			return ""
+		}
+		return "route:"+serv.Route_pattern(r)+"|"+k
+	}
+}
\ No newline at end of file
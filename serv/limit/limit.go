@@ -0,0 +1,117 @@
+package limit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+	"context"
+	"strconv"
+	"net/http"
+	"sync/atomic"
+	"github.com/clarkk/go-util/serv"
+)
+
+const (
+	TOKEN_BUCKET Algorithm = iota
+	SLIDING_WINDOW
+)
+
+var (
+	default_store = sync.OnceValue(func() *Memory {
+		return NewMemory(purge_interval_default)
+	})
+	default_prefix atomic.Uint64
+)
+
+type (
+	Algorithm int
+	
+	Options struct {
+		//	TOKEN_BUCKET allows bursts up to Limit and refills Limit tokens per Window
+		//	SLIDING_WINDOW allows Limit requests per Window weighted with the previous window
+		Algorithm	Algorithm
+		//	Requests per window
+		Limit		int
+		Window		time.Duration
+		//	Client key (Key_IP if nil)
+		Key			Key
+		//	Limit state (memory store if nil)
+		Store		Store
+		//	Key prefix to separate limits sharing a store
+		Prefix		string
+	}
+	
+	//	Store keeps limit state in memory for one node or in Redis for a cluster
+	Store interface {
+		Take(ctx context.Context, key string, algorithm Algorithm, limit int, window time.Duration) (Result, error)
+	}
+	
+	Result struct {
+		Allowed		bool
+		Limit		int
+		Remaining	int
+		//	Duration until the limit is fully reset
+		Reset		time.Duration
+		//	Duration until the next request is allowed (if not allowed)
+		Retry_after	time.Duration
+	}
+)
+
+/*
+	Rate limit adapter responding HTTP 429 with Retry-After if the limit is exceeded
+	
+	Panics if the limit or window is not positive. Adapters without store share one memory store and are separated by prefix
+*/
+func Rate(opts Options) serv.Adapter {
+	if opts.Limit < 1 || opts.Window <= 0 {
+		panic(fmt.Sprintf("Rate limit and window must be positive: limit %d, window %s", opts.Limit, opts.Window))
+	}
+	if opts.Key == nil {
+		opts.Key = Key_IP
+	}
+	if opts.Store == nil {
+		opts.Store = default_store()
+		//	Separate limits sharing the default store
+		if opts.Prefix == "" {
+			opts.Prefix = "rate"+strconv.FormatUint(default_prefix.Add(1), 10)+":"
+		}
+	}
+	
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request){
+			//	Requests without key are not limited
+			key := opts.Key(r)
+			if key == "" {
+				next(w, r)
+				return
+			}
+			
+			res, err := opts.Store.Take(r.Context(), opts.Prefix+key, opts.Algorithm, opts.Limit, opts.Window)
+			if err != nil {
+				//	Fail open if the store is unavailable
+				log.Printf("Rate limit: %v", err)
+				next(w, r)
+				return
+			}
+			
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(max(seconds(res.Retry_after), 1)))
+				serv.Error(w, r, http.StatusTooManyRequests, nil)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+//	Round duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
\ No newline at end of file
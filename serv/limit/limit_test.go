@@ -0,0 +1,141 @@
+package limit
+
+import (
+	"time"
+	"context"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_token_bucket(t *testing.T){
+	m, now := test_memory()
+	
+	//	Burst up to limit
+	for i := range 3 {
+		res := test_take(t, m, TOKEN_BUCKET)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("Request %d want allowed with %d remaining but got %+v", i, 2-i, res)
+		}
+	}
+	res := test_take(t, m, TOKEN_BUCKET)
+	if res.Allowed || res.Retry_after != 20 * time.Second {
+		t.Fatalf("Want denied with retry after 20s but got %+v", res)
+	}
+	
+	//	One token is refilled every 20 seconds
+	*now = now.Add(20 * time.Second)
+	if res := test_take(t, m, TOKEN_BUCKET); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Want allowed after refill but got %+v", res)
+	}
+	if res := test_take(t, m, TOKEN_BUCKET); res.Allowed {
+		t.Fatalf("Want denied but got %+v", res)
+	}
+}
+
+func Test_sliding_window(t *testing.T){
+	m, now := test_memory()
+	
+	for i := range 3 {
+		if res := test_take(t, m, SLIDING_WINDOW); !res.Allowed {
+			t.Fatalf("Request %d want allowed but got %+v", i, res)
+		}
+	}
+	res := test_take(t, m, SLIDING_WINDOW)
+	if res.Allowed || res.Reset != time.Minute || res.Retry_after != 80 * time.Second {
+		t.Fatalf("Want denied with reset 60s and retry after 80s but got %+v", res)
+	}
+	
+	//	Previous window still weighs 2/3 of 3 requests
+	*now = now.Add(time.Minute + 20 * time.Second)
+	if res := test_take(t, m, SLIDING_WINDOW); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Want allowed with weighted count but got %+v", res)
+	}
+	if res := test_take(t, m, SLIDING_WINDOW); res.Allowed || res.Retry_after != 20 * time.Second {
+		t.Fatalf("Want denied with retry after 20s but got %+v", res)
+	}
+}
+
+func Test_rate(t *testing.T){
+	m, _ := test_memory()
+	
+	handler := Rate(Options{
+		Limit:	1,
+		Window:	time.Minute,
+		Store:	m,
+	})(func(w http.ResponseWriter, r *http.Request){})
+	
+	request := func(ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/login", nil)
+		r.RemoteAddr = ip+":1234"
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+	
+	if w := request("10.0.0.1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("Want HTTP 200 with 0 remaining but got %d %v", w.Code, w.Header())
+	}
+	w := request("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("Want HTTP 429 with Retry-After 60 but got %d %v", w.Code, w.Header())
+	}
+	if w := request("10.0.0.2"); w.Code != http.StatusOK {
+		t.Fatalf("Want HTTP 200 for other client but got %d", w.Code)
+	}
+}
+
+func Test_rate_defaults(t *testing.T){
+	t.Run("invalid options", func(t *testing.T){
+		defer func(){
+			if recover() == nil {
+				t.Fatal("Want panic on invalid options")
+			}
+		}()
+		Rate(Options{Limit: 0, Window: time.Minute})
+	})
+	
+	t.Run("shared store", func(t *testing.T){
+		next := func(w http.ResponseWriter, r *http.Request){}
+		login	:= Rate(Options{Limit: 1, Window: time.Minute})(next)
+		api		:= Rate(Options{Limit: 1, Window: time.Minute})(next)
+		if default_store() != default_store() {
+			t.Fatal("Want one default store")
+		}
+		
+		//	Limits sharing the default store do not count each other's requests
+		for _, handler := range []http.HandlerFunc{login, api} {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.3:1234"
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Want HTTP 200 for first request but got %d", w.Code)
+			}
+		}
+	})
+	
+	t.Run("close", func(t *testing.T){
+		m := NewMemory(0)
+		m.Close()
+		m.Close()
+	})
+}
+
+//	Memory store with limit of 3 requests per minute and a fixed clock
+func test_memory() (*Memory, *time.Time){
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory(60)
+	m.now = func() time.Time {
+		return now
+	}
+	return m, &now
+}
+
+func test_take(t *testing.T, m *Memory, algorithm Algorithm) Result {
+	res, err := m.Take(context.Background(), "key", algorithm, 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
\ No newline at end of file
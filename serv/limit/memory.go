@@ -0,0 +1,77 @@
+package limit
+
+import (
+	"sync"
+	"time"
+	"context"
+)
+
+const purge_interval_default = 60
+
+type Memory struct {
+	lock		sync.Mutex
+	states		map[string]*state
+	now			func() time.Time
+	stop		chan struct{}
+	close_once	sync.Once
+}
+
+//	Create memory store for one node (expired state is purged with time interval in seconds, default 60 if 0 or less)
+func NewMemory(purge_interval int) *Memory {
+	if purge_interval <= 0 {
+		purge_interval = purge_interval_default
+	}
+	m := &Memory{
+		states:	map[string]*state{},
+		now:	time.Now,
+		stop:	make(chan struct{}),
+	}
+	ticker := time.NewTicker(time.Duration(purge_interval) * time.Second)
+	go func(){
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.purge_expired()
+			}
+		}
+	}()
+	return m
+}
+
+//	Stop purging expired state
+func (m *Memory) Close(){
+	m.close_once.Do(func(){
+		close(m.stop)
+	})
+}
+
+func (m *Memory) Take(ctx context.Context, key string, algorithm Algorithm, limit int, window time.Duration) (Result, error){
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	
+	now := m.now().UnixMilli()
+	s, ok := m.states[key]
+	if !ok || now > s.expires {
+		s = &state{}
+		m.states[key] = s
+	}
+	
+	if algorithm == SLIDING_WINDOW {
+		return s.take_sliding_window(now, limit, window.Milliseconds()), nil
+	}
+	return s.take_token_bucket(now, limit, window.Milliseconds()), nil
+}
+
+func (m *Memory) purge_expired(){
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := m.now().UnixMilli()
+	for key, s := range m.states {
+		if now > s.expires {
+			delete(m.states, key)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,113 @@
+package limit
+
+import (
+	"time"
+	"errors"
+	"context"
+	"github.com/clarkk/go-util/rdb"
+)
+
+var (
+	script_token_bucket = rdb.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = limit / window
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil then
+	tokens = limit
+elseif now > updated then
+	tokens = math.min(limit, tokens + (now - updated) * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+local reset = math.ceil((limit - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), reset, retry}
+`)
+	
+	script_sliding_window = rdb.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local start = now - now % window
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'prev', 'curr')
+local prev = 0
+local curr = 0
+if tonumber(state[1]) == start then
+	prev = tonumber(state[2])
+	curr = tonumber(state[3])
+elseif tonumber(state[1]) == start - window then
+	prev = tonumber(state[3])
+end
+
+local elapsed = now - start
+local estimate = prev * (window - elapsed) / window + curr
+
+local allowed = 0
+local retry = 0
+if estimate + 1 <= limit then
+	curr = curr + 1
+	estimate = estimate + 1
+	allowed = 1
+elseif curr < limit and prev > 0 then
+	retry = math.ceil(math.max(window - elapsed - (limit - curr - 1) * window / prev, 0))
+else
+	retry = math.ceil(window - elapsed + window * (1 - (limit - 1) / curr))
+end
+
+redis.call('HSET', KEYS[1], 'start', start, 'prev', prev, 'curr', curr)
+redis.call('PEXPIRE', KEYS[1], start + 2 * window - now)
+return {allowed, math.max(math.floor(limit - estimate), 0), window - elapsed, retry}
+`)
+)
+
+type Redis struct {
+	prefix		string
+}
+
+//	Create Redis store shared by all nodes in a cluster (rdb must be connected)
+func NewRedis(prefix string) *Redis {
+	return &Redis{
+		prefix: prefix,
+	}
+}
+
+func (rs *Redis) Take(ctx context.Context, key string, algorithm Algorithm, limit int, window time.Duration) (Result, error){
+	if !rdb.Connected() {
+		return Result{}, errors.New("Redis is not connected")
+	}
+	
+	script := script_token_bucket
+	if algorithm == SLIDING_WINDOW {
+		script = script_sliding_window
+	}
+	
+	reply, err := script.Int64s(ctx, []string{rs.prefix+key}, limit, window.Milliseconds(), time.Now().UnixMilli())
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 4 {
+		return Result{}, errors.New("Invalid rate limit script reply")
+	}
+	return Result{
+		Allowed:		reply[0] == 1,
+		Limit:			limit,
+		Remaining:		int(reply[1]),
+		Reset:			time.Duration(reply[2]) * time.Millisecond,
+		Retry_after:	time.Duration(reply[3]) * time.Millisecond,
+	}, nil
+}
\ No newline at end of file
@@ -0,0 +1,111 @@
+package req
+
+import (
+	"strings"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+)
+
+//	Loopback is trusted by default (e.g. nginx on the same host)
+var trusted_proxies atomic.Pointer[[]netip.Prefix]
+
+func init(){
+	Trusted_proxies("127.0.0.0/8", "::1/128")
+}
+
+//	Set trusted proxy CIDRs or IPs allowed to report the client IP via Forwarded, X-Forwarded-For and X-Real-Ip
+func Trusted_proxies(cidrs ...string) error {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		var (
+			prefix	netip.Prefix
+			err		error
+		)
+		if strings.Contains(cidr, "/") {
+			prefix, err = netip.ParsePrefix(cidr)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(cidr)
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	trusted_proxies.Store(&prefixes)
+	return nil
+}
+
+//	Check if address is a trusted proxy
+func trusted_proxy(addr netip.Addr) bool {
+	for _, prefix := range *trusted_proxies.Load() {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+//	Walk forwarded addresses right to left and return the first address which is not a trusted proxy
+func forwarded_client(peer netip.Addr, forwarded []string) netip.Addr {
+	client := peer
+	for i := len(forwarded)-1; i >= 0; i-- {
+		addr, ok := parse_forwarded_addr(forwarded[i])
+		if !ok {
+			//	Obfuscated or invalid address can not be verified
+			break
+		}
+		client = addr
+		if !trusted_proxy(addr) {
+			break
+		}
+	}
+	return client
+}
+
+//	Get addresses from X-Forwarded-For headers
+func x_forwarded_for(r *http.Request) []string {
+	var list []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(header, ",") {
+			list = append(list, strings.TrimSpace(addr))
+		}
+	}
+	return list
+}
+
+//	Get "for" addresses from RFC 7239 Forwarded headers: for=192.0.2.43, for="[2001:db8::1]:4711"
+func forwarded_for(r *http.Request) []string {
+	var list []string
+	for _, header := range r.Header.Values("Forwarded") {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					list = append(list, strings.Trim(value, `"`))
+				}
+			}
+		}
+	}
+	return list
+}
+
+//	Parse forwarded address with optional port: 192.0.2.43, 192.0.2.43:47011, [2001:db8::1]:4711
+func parse_forwarded_addr(s string) (netip.Addr, bool){
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end == -1 {
+			return netip.Addr{}, false
+		}
+		s = s[1:end]
+	} else if strings.Count(s, ":") == 1 {
+		s, _, _ = strings.Cut(s, ":")
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
\ No newline at end of file
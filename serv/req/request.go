@@ -3,27 +3,75 @@ package req
 import (
 	"io"
 	"net"
+	"regexp"
 	"strings"
+	"context"
 	"net/http"
+	"net/netip"
+	"github.com/google/uuid"
 )
 
+const (
+	REQUEST_ID = "X-Request-ID"
+	
+	ctx_request_id ctx_key = "request_id"
+)
+
+var re_request_id = regexp.MustCompile(`^[\w.:\-]{1,128}$`)
+
+type ctx_key string
+
+//	Get client IP (proxy headers are only accepted from trusted proxies)
 func Get_client_IP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	peer = peer.Unmap()
+	if !trusted_proxy(peer) {
+		return peer.String()
 	}
 	
-	if xri := r.Header.Get("X-Real-Ip"); xri != "" {
-		return xri
+	if forwarded := forwarded_for(r); len(forwarded) > 0 {
+		return forwarded_client(peer, forwarded).String()
 	}
 	
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
+	if xff := x_forwarded_for(r); len(xff) > 0 {
+		return forwarded_client(peer, xff).String()
+	}
+	
+	if xri, ok := parse_forwarded_addr(strings.TrimSpace(r.Header.Get("X-Real-Ip"))); ok {
+		return xri.String()
+	}
+	return peer.String()
+}
+
+//	Get request ID from request context
+func Request_ID(r *http.Request) string {
+	return Context_request_ID(r.Context())
+}
+
+//	Get request ID from context (e.g. to forward to outbound requests)
+func Context_request_ID(ctx context.Context) string {
+	id, _ := ctx.Value(ctx_request_id).(string)
+	return id
+}
+
+//	Store request ID in context
+func With_request_ID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctx_request_id, id)
+}
+
+//	Accept request ID from X-Request-ID header if valid or generate a new request ID
+func Accept_request_ID(r *http.Request) string {
+	if id := r.Header.Get(REQUEST_ID); re_request_id.MatchString(id) {
+		return id
 	}
-	return host
+	return uuid.NewString()
 }
 
 //	Get all path slugs in URL
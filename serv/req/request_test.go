@@ -0,0 +1,55 @@
+package req
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func Test_get_client_IP(t *testing.T){
+	if err := Trusted_proxies("127.0.0.1", "10.0.0.0/8", "2001:db8::/32"); err != nil {
+		t.Fatal(err)
+	}
+	defer Trusted_proxies("127.0.0.0/8", "::1/128")
+	
+	tests := []struct{
+		name		string
+		remote		string
+		header		map[string]string
+		want		string
+	}{
+		{"untrusted peer", "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-Ip": "1.2.3.4"}, "203.0.113.9"},
+		{"no proxy headers", "127.0.0.1:1234", nil, "127.0.0.1"},
+		{"xff", "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"xff spoofed", "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.1.1.1"}, "198.51.100.7"},
+		{"xff all trusted", "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.2.2.2, 10.1.1.1"}, "10.2.2.2"},
+		{"xff invalid", "127.0.0.1:1234", map[string]string{"X-Forwarded-For": "garbage, 10.1.1.1"}, "10.1.1.1"},
+		{"forwarded", "[2001:db8::1]:1234", map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711", for=198.51.100.7;proto=https`}, "198.51.100.7"},
+		{"forwarded ipv6", "127.0.0.1:1234", map[string]string{"Forwarded": `For="[2001:dc8::17]:4711";proto=https`, "X-Forwarded-For": "1.2.3.4"}, "2001:dc8::17"},
+		{"forwarded obfuscated", "127.0.0.1:1234", map[string]string{"Forwarded": "for=unknown, for=192.0.2.60:8080"}, "192.0.2.60"},
+		{"real ip", "127.0.0.1:1234", map[string]string{"X-Real-Ip": "198.51.100.7"}, "198.51.100.7"},
+		{"ipv4 mapped peer", "[::ffff:10.0.0.1]:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for key, value := range tt.header {
+				r.Header.Set(key, value)
+			}
+			if got := Get_client_IP(r); got != tt.want {
+				t.Fatalf("Client IP want %s but got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_trusted_proxies_invalid(t *testing.T){
+	defer Trusted_proxies("127.0.0.0/8", "::1/128")
+	
+	for _, cidr := range []string{"10.0.0.0/33", "localhost"} {
+		if err := Trusted_proxies(cidr); err == nil {
+			t.Fatalf("Trusted proxy %s want error", cidr)
+		}
+	}
+}
\ No newline at end of file
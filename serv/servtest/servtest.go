@@ -0,0 +1,93 @@
+package servtest
+
+import (
+	"io"
+	"testing"
+	"strings"
+	"net/url"
+	"net/http"
+	"net/http/httptest"
+	"net/http/cookiejar"
+	"github.com/clarkk/go-util/serv"
+)
+
+type Client struct {
+	handler		http.Handler
+	tld			string
+	jar			*cookiejar.Jar
+	//	Headers sent with every request
+	Header		http.Header
+}
+
+//	Create in-process client sending requests through the server in test mode (routes are validated first)
+func New(t testing.TB, h *serv.HTTP) *Client {
+	t.Helper()
+	
+	h.Test()
+	if err := h.Validate(); err != nil {
+		t.Fatalf("HTTP server: %s", err)
+	}
+	handler, err := h.Test_handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		handler:	handler,
+		tld:		h.TLD(),
+		jar:		jar,
+		Header:		http.Header{},
+	}
+}
+
+//	Send GET request to subhost ("api." or "" for the apex domain) and path
+func (c *Client) Get(subhost, path string) *httptest.ResponseRecorder {
+	return c.Do(c.Request(http.MethodGet, subhost, path, nil))
+}
+
+//	Send POST request to subhost and path
+func (c *Client) Post(subhost, path, content_type string, body io.Reader) *httptest.ResponseRecorder {
+	r := c.Request(http.MethodPost, subhost, path, body)
+	r.Header.Set("Content-Type", content_type)
+	return c.Do(r)
+}
+
+//	Send POST request with form values to subhost and path
+func (c *Client) Post_form(subhost, path string, values url.Values) *httptest.ResponseRecorder {
+	return c.Post(subhost, path, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+//	Create HTTPS request to subhost and path (secure cookies are sent by the cookie jar)
+func (c *Client) Request(method, subhost, path string, body io.Reader) *http.Request {
+	return httptest.NewRequest(method, "https://"+subhost+c.tld+path, body)
+}
+
+//	Send request with cookies from the cookie jar and store cookies from the response
+func (c *Client) Do(r *http.Request) *httptest.ResponseRecorder {
+	for key, values := range c.Header {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	for _, cookie := range c.jar.Cookies(r.URL) {
+		r.AddCookie(cookie)
+	}
+	
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, r)
+	
+	c.jar.SetCookies(r.URL, w.Result().Cookies())
+	return w
+}
+
+//	Get cookies stored in the cookie jar for subhost
+func (c *Client) Cookies(subhost string) []*http.Cookie {
+	return c.jar.Cookies(&url.URL{
+		Scheme:	"https",
+		Host:	subhost+c.tld,
+		Path:	"/",
+	})
+}
\ No newline at end of file
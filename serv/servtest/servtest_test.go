@@ -0,0 +1,56 @@
+package servtest
+
+import (
+	"io"
+	"testing"
+	"net/url"
+	"net/http"
+	"github.com/clarkk/go-util/serv"
+)
+
+func Test_client(t *testing.T){
+	h := serv.NewHTTP("domain.com", "", 0)
+	h.Subhost("api.").
+		Route(serv.POST, "/login", 0, func(w http.ResponseWriter, r *http.Request){
+			r.ParseForm()
+			serv.Set_cookie(w, "user", r.PostForm.Get("user"), 60)
+			w.WriteHeader(http.StatusNoContent)
+		}).
+		Route(serv.GET, "/me", 0, func(w http.ResponseWriter, r *http.Request){
+			cookie, err := r.Cookie("user")
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, cookie.Value+" "+r.Header.Get("X-Test"))
+		})
+	h.Apex().
+		Route(serv.GET, "/", 0, func(w http.ResponseWriter, r *http.Request){
+			io.WriteString(w, "apex")
+		})
+	
+	c := New(t, h)
+	c.Header.Set("X-Test", "header")
+	
+	if w := c.Get("api.", "/me"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Want HTTP 401 before login but got %d", w.Code)
+	}
+	if w := c.Post_form("api.", "/login", url.Values{"user": {"john"}}); w.Code != http.StatusNoContent {
+		t.Fatalf("Login want HTTP 204 but got %d", w.Code)
+	}
+	if len(c.Cookies("api.")) != 1 {
+		t.Fatalf("Cookie jar want 1 cookie but got %v", c.Cookies("api."))
+	}
+	if w := c.Get("api.", "/me"); w.Code != http.StatusOK || w.Body.String() != "john header" {
+		t.Fatalf("Want HTTP 200 [john header] but got %d [%s]", w.Code, w.Body.String())
+	}
+	if w := c.Get("", "/"); w.Body.String() != "apex" {
+		t.Fatalf("Apex want [apex] but got [%s]", w.Body.String())
+	}
+}
+
+func Test_test_mode(t *testing.T){
+	if _, err := serv.NewHTTP("domain.com", "", 0).Test_handler(); err == nil {
+		t.Fatal("Test handler want error outside test mode")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,136 @@
+package sse
+
+import (
+	"log"
+	"sync"
+	"errors"
+	"strconv"
+	"net/http"
+	"github.com/clarkk/go-util/serv/req"
+)
+
+var ErrSlow_subscriber = errors.New("Subscriber is too slow")
+
+//	Broadcast hub fanning events out to subscribers
+type Hub struct {
+	lock			sync.Mutex
+	subscribers		map[chan Event]struct{}
+	buffer			int
+	history			[]Event
+	history_size	int
+	seq				uint64
+}
+
+//	Create hub where each subscriber buffers up to buffer events and the last history events are replayed on resumption
+func NewHub(buffer, history int) *Hub {
+	return &Hub{
+		subscribers:	map[chan Event]struct{}{},
+		buffer:			max(buffer, 1),
+		history_size:	max(history, 0),
+	}
+}
+
+/*
+	Publish event to all subscribers (events without ID are numbered by the hub)
+	
+	Subscribers with a full buffer are disconnected and can reconnect with Last-Event-ID to resume from the history
+*/
+func (h *Hub) Publish(e Event){
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if e.ID == "" {
+		h.seq++
+		e.ID = strconv.FormatUint(h.seq, 10)
+	}
+	if h.history_size > 0 {
+		if len(h.history) == h.history_size {
+			h.history = append(h.history[:0], h.history[1:]...)
+		}
+		h.history = append(h.history, e)
+	}
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+/*
+	Subscribe to events published after the event with last_event_id (the full history is replayed if the ID is unknown)
+	
+	The channel is closed if the subscriber is too slow. Call the returned function to unsubscribe
+*/
+func (h *Hub) Subscribe(last_event_id string) (<-chan Event, func()){
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	replay := h.replay(last_event_id)
+	ch := make(chan Event, h.buffer+len(replay))
+	for _, e := range replay {
+		ch <- e
+	}
+	h.subscribers[ch] = struct{}{}
+	return ch, func(){
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+//	Number of subscribers
+func (h *Hub) Subscribers() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.subscribers)
+}
+
+//	Send published events to the stream until the client disconnects
+func (h *Hub) Serve(s *Stream) error {
+	ch, unsubscribe := h.Subscribe(s.Last_event_ID())
+	defer unsubscribe()
+	for {
+		select {
+		case <-s.Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return ErrSlow_subscriber
+			}
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//	Handler opening an event stream for each client subscribed to the hub
+func (h *Hub) Handler(opts ...Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request){
+		s, err := New(w, r, opts...)
+		if err != nil {
+			log.Printf("Request ID %s: SSE: %v", req.Request_ID(r), err)
+			return
+		}
+		defer s.Close()
+		if err := h.Serve(s); err != nil && r.Context().Err() == nil {
+			log.Printf("Request ID %s: SSE: %v", req.Request_ID(r), err)
+		}
+	}
+}
+
+func (h *Hub) replay(last_event_id string) []Event {
+	if last_event_id == "" {
+		return nil
+	}
+	for i, e := range h.history {
+		if e.ID == last_event_id {
+			return h.history[i+1:]
+		}
+	}
+	return h.history
+}
\ No newline at end of file
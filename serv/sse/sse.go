@@ -0,0 +1,220 @@
+package sse
+
+import (
+	"sync"
+	"time"
+	"errors"
+	"context"
+	"strconv"
+	"strings"
+	"net/http"
+)
+
+const (
+	TYPE_EVENT_STREAM		= "text/event-stream"
+	
+	keep_alive_interval		= 15 * time.Second
+)
+
+var ErrClosed = errors.New("Stream is closed")
+
+type (
+	Options struct {
+		//	Interval between keep-alive comments (default 15 seconds, negative disables)
+		Keep_alive	time.Duration
+		//	Client reconnection delay sent when the stream opens (not sent if 0)
+		Retry		time.Duration
+	}
+	
+	Event struct {
+		ID		string
+		//	Event name (the client dispatches "message" if empty)
+		Event	string
+		//	Multiline data is sent as multiple data fields
+		Data	string
+		//	Client reconnection delay (not sent if 0)
+		Retry	time.Duration
+	}
+	
+	Stream struct {
+		w				http.ResponseWriter
+		rc				*http.ResponseController
+		ctx				context.Context
+		last_event_id	string
+		lock			sync.Mutex
+		closed			bool
+		stop			chan struct{}
+		done			chan struct{}
+	}
+)
+
+/*
+	Open event stream and send keep-alive comments until the client disconnects or the stream is closed
+	
+	The stream must be closed before the handler returns (defer s.Close()). Routes serving streams should not have a timeout
+*/
+func New(w http.ResponseWriter, r *http.Request, opts ...Options) (*Stream, error){
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Keep_alive == 0 {
+		o.Keep_alive = keep_alive_interval
+	}
+	
+	s := &Stream{
+		w:				w,
+		rc:				http.NewResponseController(w),
+		ctx:			r.Context(),
+		last_event_id:	r.Header.Get("Last-Event-ID"),
+		stop:			make(chan struct{}),
+		done:			make(chan struct{}),
+	}
+	
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Type", TYPE_EVENT_STREAM)
+	header.Set("Cache-Control", "no-cache")
+	//	Disable response buffering in nginx
+	header.Set("X-Accel-Buffering", "no")
+	
+	//	Streams outlive the server write timeout (not supported by all writers)
+	s.rc.SetWriteDeadline(time.Time{})
+	
+	w.WriteHeader(http.StatusOK)
+	var b []byte
+	if o.Retry > 0 {
+		b = append_retry(b, o.Retry)
+		b = append(b, '\n')
+	}
+	if err := s.write(b); err != nil {
+		close(s.done)
+		return nil, err
+	}
+	
+	if o.Keep_alive > 0 {
+		go s.keep_alive(o.Keep_alive)
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+//	Send event and flush
+func (s *Stream) Send(e Event) error {
+	return s.write(e.append(nil))
+}
+
+//	Send comment ignored by the client
+func (s *Stream) Comment(text string) error {
+	var b []byte
+	for line := range strings.SplitSeq(normalize_newlines(text), "\n") {
+		b = append(b, ": "...)
+		b = append(b, line...)
+		b = append(b, '\n')
+	}
+	return s.write(append(b, '\n'))
+}
+
+//	Get ID of the last event received by the client before reconnecting (Last-Event-ID header)
+func (s *Stream) Last_event_ID() string {
+	return s.last_event_id
+}
+
+//	Channel closed when the client disconnects
+func (s *Stream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+//	Stop keep-alive comments and wait until they are stopped
+func (s *Stream) Close(){
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	s.lock.Unlock()
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Stream) keep_alive(interval time.Duration){
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Stream) write(b []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		if _, err := s.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return s.rc.Flush()
+}
+
+func (e Event) append(b []byte) []byte {
+	if e.ID != "" {
+		b = append(b, "id: "...)
+		b = append(b, single_line(e.ID)...)
+		b = append(b, '\n')
+	}
+	if e.Event != "" {
+		b = append(b, "event: "...)
+		b = append(b, single_line(e.Event)...)
+		b = append(b, '\n')
+	}
+	if e.Retry > 0 {
+		b = append_retry(b, e.Retry)
+	}
+	if e.Data != "" {
+		for line := range strings.SplitSeq(normalize_newlines(e.Data), "\n") {
+			b = append(b, "data: "...)
+			b = append(b, line...)
+			b = append(b, '\n')
+		}
+	}
+	return append(b, '\n')
+}
+
+func append_retry(b []byte, retry time.Duration) []byte {
+	b = append(b, "retry: "...)
+	b = strconv.AppendInt(b, retry.Milliseconds(), 10)
+	return append(b, '\n')
+}
+
+func normalize_newlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+//	Strip characters that would end the field (IDs with NUL are ignored by clients)
+func single_line(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', 0:
+			return -1
+		}
+		return r
+	}, s)
+}
\ No newline at end of file
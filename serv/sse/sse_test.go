@@ -0,0 +1,166 @@
+package sse
+
+import (
+	"time"
+	"bufio"
+	"context"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"github.com/clarkk/go-util/serv"
+)
+
+func Test_event(t *testing.T){
+	for e, want := range map[Event]string{
+		{Data: "hello"}:	"data: hello\n\n",
+		{
+			ID:		"7\n",
+			Event:	"update",
+			Data:	"a\r\nb\rc",
+			Retry:	2 * time.Second,
+		}:					"id: 7\nevent: update\nretry: 2000\ndata: a\ndata: b\ndata: c\n\n",
+		{ID: "8"}:			"id: 8\n\n",
+	} {
+		if got := string(e.append(nil)); got != want {
+			t.Fatalf("Event want %q but got %q", want, got)
+		}
+	}
+}
+
+func Test_stream(t *testing.T){
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		defer close(done)
+		s, err := New(serv.NewWriter(w), r, Options{
+			Keep_alive:	20 * time.Millisecond,
+			Retry:		3 * time.Second,
+		})
+		if err != nil {
+			t.Errorf("Unable to open stream: %s", err)
+			return
+		}
+		defer s.Close()
+		if s.Last_event_ID() != "41" {
+			t.Errorf("Last-Event-ID want 41 but got [%s]", s.Last_event_ID())
+		}
+		s.Send(Event{ID: "42", Data: "hello"})
+		<-s.Done()
+		if err := s.Send(Event{Data: "gone"}); err == nil {
+			t.Error("Send want error after disconnect")
+		}
+	}))
+	defer srv.Close()
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := test_connect(t, ctx, srv.URL, "41")
+	for _, want := range []string{"retry: 3000", "", "id: 42", "data: hello", "", ": keep-alive", ""} {
+		if got := test_line(t, lines); got != want {
+			t.Fatalf("Line want %q but got %q", want, got)
+		}
+	}
+	
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Handler did not return after disconnect")
+	}
+}
+
+func Test_hub(t *testing.T){
+	hub := NewHub(8, 2)
+	for range 3 {
+		hub.Publish(Event{Data: "old"})
+	}
+	
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		hub.Handler(Options{Keep_alive: -1})(serv.NewWriter(w), r)
+	}))
+	defer srv.Close()
+	
+	t.Run("resume", func(t *testing.T){
+		ctx, cancel := context.WithCancel(context.Background())
+		lines := test_connect(t, ctx, srv.URL, "2")
+		test_wait(t, func() bool {
+			return hub.Subscribers() == 1
+		})
+		hub.Publish(Event{Event: "tick", Data: "new"})
+		for _, want := range []string{"id: 3", "data: old", "", "id: 4", "event: tick", "data: new", ""} {
+			if got := test_line(t, lines); got != want {
+				t.Fatalf("Line want %q but got %q", want, got)
+			}
+		}
+		
+		cancel()
+		test_wait(t, func() bool {
+			return hub.Subscribers() == 0
+		})
+	})
+	
+	t.Run("slow subscriber", func(t *testing.T){
+		hub := NewHub(1, 0)
+		ch, unsubscribe := hub.Subscribe("")
+		hub.Publish(Event{Data: "first"})
+		hub.Publish(Event{Data: "second"})
+		if e := <-ch; e.Data != "first" {
+			t.Fatalf("Event want first but got %s", e.Data)
+		}
+		if _, ok := <-ch; ok {
+			t.Fatal("Channel want closed for slow subscriber")
+		}
+		if n := hub.Subscribers(); n != 0 {
+			t.Fatalf("Subscribers want 0 but got %d", n)
+		}
+		unsubscribe()
+	})
+}
+
+func test_connect(t *testing.T, ctx context.Context, url, last_event_id string) <-chan string {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	r.Header.Set("Last-Event-ID", last_event_id)
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != TYPE_EVENT_STREAM {
+		t.Fatalf("Content-Type want %s but got %s", TYPE_EVENT_STREAM, ct)
+	}
+	
+	lines := make(chan string)
+	go func(){
+		defer res.Body.Close()
+		defer close(lines)
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func test_line(t *testing.T, lines <-chan string) string {
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			t.Fatal("Stream closed")
+		}
+		return line
+	case <-time.After(time.Second):
+		t.Fatal("Timeout reading stream")
+	}
+	return ""
+}
+
+func test_wait(t *testing.T, cond func() bool){
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
\ No newline at end of file
@@ -1,11 +1,34 @@
 package serv
 
 import (
+	"errors"
 	"context"
 	"net/http"
 )
 
+//	Get handler serving requests in memory without a listener (requires test mode)
+func (h *HTTP) Test_handler() (http.Handler, error){
+	if !h.test {
+		return nil, errors.New("HTTP server is not in test mode")
+	}
+	return http.HandlerFunc(h.serve), nil
+}
+
 func Test_set_slugs(r *http.Request, slugs ...string) *http.Request {
 	ctx := context.WithValue(r.Context(), ctx_slug, slugs)
 	return r.WithContext(ctx)
+}
+
+func Test_set_params(r *http.Request, params map[string]string) *http.Request {
+	var (
+		names 	[]string
+		slugs 	[]string
+	)
+	for name, value := range params {
+		names = append(names, name)
+		slugs = append(slugs, value)
+	}
+	ctx := context.WithValue(r.Context(), ctx_slug, slugs)
+	ctx = context.WithValue(ctx, ctx_param, names)
+	return r.WithContext(ctx)
 }
\ No newline at end of file
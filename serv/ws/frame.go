@@ -0,0 +1,111 @@
+package ws
+
+import (
+	"io"
+	"bufio"
+	"encoding/binary"
+)
+
+const (
+	op_continuation	byte = 0x0
+	op_text			byte = 0x1
+	op_binary		byte = 0x2
+	op_close		byte = 0x8
+	op_ping			byte = 0x9
+	op_pong			byte = 0xA
+	
+	bit_fin			= 0x80
+	bit_rsv			= 0x70
+	bit_mask		= 0x80
+	
+	max_control_payload	= 125
+)
+
+type frame_header struct {
+	fin		bool
+	opcode	byte
+	length	int64
+	mask	[4]byte
+}
+
+//	Read client frame header (client frames must be masked and extensions are not negotiated)
+func read_frame_header(br *bufio.Reader) (frame_header, error){
+	var h frame_header
+	var b [8]byte
+	if _, err := io.ReadFull(br, b[:2]); err != nil {
+		return h, err
+	}
+	if b[0] & bit_rsv != 0 {
+		return h, protocol_error("Reserved bits are set")
+	}
+	h.fin		= b[0] & bit_fin != 0
+	h.opcode	= b[0] & 0x0F
+	switch h.opcode {
+	case op_continuation, op_text, op_binary, op_close, op_ping, op_pong:
+	default:
+		return h, protocol_error("Unknown opcode")
+	}
+	if b[1] & bit_mask == 0 {
+		return h, protocol_error("Client frame is not masked")
+	}
+	
+	switch length := b[1] & 0x7F; length {
+	case 126:
+		if _, err := io.ReadFull(br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(br, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n >> 63 != 0 {
+			return h, protocol_error("Invalid payload length")
+		}
+		h.length = int64(n)
+	default:
+		h.length = int64(length)
+	}
+	
+	if is_control(h.opcode) && (!h.fin || h.length > max_control_payload) {
+		return h, protocol_error("Control frame is fragmented or too large")
+	}
+	if _, err := io.ReadFull(br, h.mask[:]); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+//	Read and unmask payload
+func read_payload(br *bufio.Reader, h frame_header, b []byte) ([]byte, error){
+	start := len(b)
+	b = append(b, make([]byte, h.length)...)
+	if _, err := io.ReadFull(br, b[start:]); err != nil {
+		return nil, err
+	}
+	for i := range b[start:] {
+		b[start+i] ^= h.mask[i & 3]
+	}
+	return b, nil
+}
+
+//	Append server frame (server frames are not masked)
+func append_frame(b []byte, opcode byte, payload []byte) []byte {
+	b = append(b, bit_fin | opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, byte(n))
+	case n <= 0xFFFF:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	return append(b, payload...)
+}
+
+func is_control(opcode byte) bool {
+	return opcode & 0x8 != 0
+}
\ No newline at end of file
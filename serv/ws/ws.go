@@ -0,0 +1,405 @@
+package ws
+
+import (
+	"net"
+	"sync"
+	"time"
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"net/http"
+	"crypto/sha1"
+	"unicode/utf8"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/clarkk/go-util/serv"
+	"github.com/clarkk/go-util/sess"
+)
+
+const (
+	TEXT Message_type		= Message_type(op_text)
+	BINARY Message_type		= Message_type(op_binary)
+	
+	CLOSE_NORMAL			= 1000
+	CLOSE_GOING_AWAY		= 1001
+	CLOSE_PROTOCOL_ERROR	= 1002
+	CLOSE_UNSUPPORTED_DATA	= 1003
+	CLOSE_NO_STATUS			= 1005
+	CLOSE_INVALID_DATA		= 1007
+	CLOSE_POLICY_VIOLATION	= 1008
+	CLOSE_TOO_LARGE			= 1009
+	CLOSE_INTERNAL_ERROR	= 1011
+	
+	accept_guid				= "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	max_message_size		= 1 << 20
+	read_timeout			= 60 * time.Second
+	write_timeout			= 10 * time.Second
+)
+
+var ErrClosed = errors.New("Connection is closed")
+
+type (
+	Message_type int
+	
+	Options struct {
+		//	Maximum message size in bytes (default 1 MB)
+		Max_message_size	int64
+		//	Deadline for reading each message, extended by control frames (default 60 seconds, negative disables)
+		Read_timeout		time.Duration
+		//	Deadline for writing each frame (default 10 seconds, negative disables)
+		Write_timeout		time.Duration
+		//	Subprotocols supported by the server in order of preference
+		Subprotocols		[]string
+		//	Origin check before upgrading (sess.Verify_origin if nil, which rejects all origins without sess.Init_CSRF)
+		Check_origin		func(r *http.Request) bool
+	}
+	
+	//	Connection is safe for one concurrent reader and multiple concurrent writers
+	Conn struct {
+		conn			net.Conn
+		br				*bufio.Reader
+		opts			Options
+		subprotocol		string
+		write_lock		sync.Mutex
+		close_sent		bool
+	}
+	
+	//	Close frame received from the client or sent on protocol violation
+	Close_error struct {
+		Code	int
+		Reason	string
+	}
+)
+
+/*
+	Upgrade HTTP request to WebSocket connection (RFC 6455)
+	
+	Requests failing the origin check are rejected with HTTP 403. Sessions should be started, verified and closed before upgrading,
+	so the session lock is not held by the connection. Routes serving connections should not have a timeout
+*/
+func Upgrade(w http.ResponseWriter, r *http.Request, opts ...Options) (*Conn, error){
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Max_message_size == 0 {
+		o.Max_message_size = max_message_size
+	}
+	if o.Read_timeout == 0 {
+		o.Read_timeout = read_timeout
+	}
+	if o.Write_timeout == 0 {
+		o.Write_timeout = write_timeout
+	}
+	if o.Check_origin == nil {
+		o.Check_origin = sess.Verify_origin
+	}
+	
+	key, status, err := handshake(r)
+	if err == nil && !o.Check_origin(r) {
+		status, err = http.StatusForbidden, errors.New("Origin is not allowed")
+	}
+	if err != nil {
+		if status == http.StatusUpgradeRequired {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+		}
+		serv.Error(w, r, status, err)
+		return nil, err
+	}
+	
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		serv.Error(w, r, http.StatusInternalServerError, err)
+		return nil, err
+	}
+	//	Clear server timeouts set on the connection
+	conn.SetDeadline(time.Time{})
+	
+	c := &Conn{
+		conn:			conn,
+		br:				brw.Reader,
+		opts:			o,
+		subprotocol:	negotiate_subprotocol(r, o.Subprotocols),
+	}
+	
+	b := []byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: "+accept_key(key)+"\r\n")
+	if c.subprotocol != "" {
+		b = append(b, "Sec-WebSocket-Protocol: "+c.subprotocol+"\r\n"...)
+	}
+	b = append(b, "\r\n"...)
+	c.set_write_deadline()
+	if _, err := conn.Write(b); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+//	WebSocket route handler closing the connection when fn returns
+func Handler(fn func(c *Conn, r *http.Request), opts ...Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request){
+		c, err := Upgrade(w, r, opts...)
+		if err != nil {
+			return
+		}
+		defer c.Close(CLOSE_NORMAL, "")
+		fn(c, r)
+	}
+}
+
+//	Subprotocol negotiated in the handshake (empty if none)
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+/*
+	Read next text or binary message (fragmented messages are reassembled)
+	
+	Pings are answered with pongs. A close frame from the client is echoed and returned as *Close_error.
+	On protocol violations, oversized messages or invalid UTF-8 text the connection is closed with the corresponding close code
+*/
+func (c *Conn) Read_message() (Message_type, []byte, error){
+	var (
+		opcode	byte
+		msg		[]byte
+	)
+	c.set_read_deadline()
+	for {
+		h, err := read_frame_header(c.br)
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+		
+		if is_control(h.opcode) {
+			payload, err := read_payload(c.br, h, nil)
+			if err != nil {
+				return 0, nil, c.fail(err)
+			}
+			if err := c.control(h.opcode, payload); err != nil {
+				return 0, nil, err
+			}
+			c.set_read_deadline()
+			continue
+		}
+		
+		if h.opcode == op_continuation && opcode == 0 {
+			return 0, nil, c.fail(protocol_error("Unexpected continuation frame"))
+		}
+		if h.opcode != op_continuation && opcode != 0 {
+			return 0, nil, c.fail(protocol_error("Expected continuation frame"))
+		}
+		if opcode == 0 {
+			opcode = h.opcode
+		}
+		if int64(len(msg)) + h.length > c.opts.Max_message_size {
+			return 0, nil, c.fail(&Close_error{
+				Code:	CLOSE_TOO_LARGE,
+				Reason:	"Message is too large",
+			})
+		}
+		if msg, err = read_payload(c.br, h, msg); err != nil {
+			return 0, nil, c.fail(err)
+		}
+		if h.fin {
+			break
+		}
+	}
+	
+	if opcode == op_text && !utf8.Valid(msg) {
+		return 0, nil, c.fail(&Close_error{
+			Code:	CLOSE_INVALID_DATA,
+			Reason:	"Invalid UTF-8",
+		})
+	}
+	return Message_type(opcode), msg, nil
+}
+
+//	Write text or binary message
+func (c *Conn) Write_message(typ Message_type, data []byte) error {
+	if typ != TEXT && typ != BINARY {
+		return errors.New("Invalid message type")
+	}
+	return c.write_frame(byte(typ), data)
+}
+
+//	Send ping (the pong is handled while reading messages)
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > max_control_payload {
+		return errors.New("Ping payload is too large")
+	}
+	return c.write_frame(op_ping, data)
+}
+
+//	Send close frame and close the connection
+func (c *Conn) Close(code int, reason string) error {
+	err := c.write_close(code, reason)
+	c.conn.Close()
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (e *Close_error) Error() string {
+	s := "WebSocket closed with code "+strconv.Itoa(e.Code)
+	if e.Reason != "" {
+		s += ": "+e.Reason
+	}
+	return s
+}
+
+func (c *Conn) control(opcode byte, payload []byte) error {
+	switch opcode {
+	case op_ping:
+		if err := c.write_frame(op_pong, payload); err != nil {
+			return c.fail(err)
+		}
+	case op_close:
+		e := &Close_error{
+			Code:	CLOSE_NO_STATUS,
+		}
+		if len(payload) == 1 {
+			return c.fail(protocol_error("Invalid close frame"))
+		}
+		if len(payload) >= 2 {
+			e.Code		= int(binary.BigEndian.Uint16(payload))
+			e.Reason	= string(payload[2:])
+			if !valid_close_code(e.Code) || !utf8.ValidString(e.Reason) {
+				return c.fail(protocol_error("Invalid close code or reason"))
+			}
+		}
+		//	Echo close code
+		c.write_close(e.Code, "")
+		c.conn.Close()
+		return e
+	}
+	return nil
+}
+
+//	Close connection (with close frame on protocol errors) and return the error
+func (c *Conn) fail(err error) error {
+	var e *Close_error
+	if errors.As(err, &e) {
+		c.write_close(e.Code, e.Reason)
+	}
+	c.conn.Close()
+	return err
+}
+
+func (c *Conn) write_close(code int, reason string) error {
+	c.write_lock.Lock()
+	defer c.write_lock.Unlock()
+	if c.close_sent {
+		return ErrClosed
+	}
+	c.close_sent = true
+	var payload []byte
+	if code != CLOSE_NO_STATUS {
+		payload = binary.BigEndian.AppendUint16(payload, uint16(code))
+		payload = append(payload, truncate_utf8(reason, max_control_payload-2)...)
+	}
+	return c.write(op_close, payload)
+}
+
+func (c *Conn) write_frame(opcode byte, payload []byte) error {
+	c.write_lock.Lock()
+	defer c.write_lock.Unlock()
+	if c.close_sent {
+		return ErrClosed
+	}
+	return c.write(opcode, payload)
+}
+
+func (c *Conn) write(opcode byte, payload []byte) error {
+	c.set_write_deadline()
+	_, err := c.conn.Write(append_frame(nil, opcode, payload))
+	return err
+}
+
+func (c *Conn) set_read_deadline(){
+	if c.opts.Read_timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.Read_timeout))
+	}
+}
+
+func (c *Conn) set_write_deadline(){
+	if c.opts.Write_timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.opts.Write_timeout))
+	}
+}
+
+//	Validate upgrade request and get the client key
+func handshake(r *http.Request) (key string, status int, err error){
+	if r.ProtoMajor != 1 {
+		return "", http.StatusHTTPVersionNotSupported, errors.New("WebSocket requires HTTP/1.1")
+	}
+	if r.Method != http.MethodGet {
+		return "", http.StatusMethodNotAllowed, errors.New("WebSocket upgrade requires GET")
+	}
+	if !header_token(r.Header, "Connection", "upgrade") || !header_token(r.Header, "Upgrade", "websocket") {
+		return "", http.StatusBadRequest, errors.New("Missing WebSocket upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", http.StatusUpgradeRequired, errors.New("Unsupported WebSocket version")
+	}
+	key = r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return "", http.StatusBadRequest, errors.New("Invalid WebSocket key")
+	}
+	return key, 0, nil
+}
+
+func accept_key(key string) string {
+	sum := sha1.Sum([]byte(key+accept_guid))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+//	Select the first subprotocol supported by the server that is requested by the client
+func negotiate_subprotocol(r *http.Request, supported []string) string {
+	for _, protocol := range supported {
+		if header_token(r.Header, "Sec-WebSocket-Protocol", protocol) {
+			return protocol
+		}
+	}
+	return ""
+}
+
+//	Check if comma-separated header values contain token (case-insensitive)
+func header_token(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for v := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valid_close_code(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1014:
+		return code != 1004 && code != 1005 && code != 1006
+	}
+	return false
+}
+
+func truncate_utf8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func protocol_error(reason string) *Close_error {
+	return &Close_error{
+		Code:	CLOSE_PROTOCOL_ERROR,
+		Reason:	reason,
+	}
+}
\ No newline at end of file
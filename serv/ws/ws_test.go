@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"io"
+	"net"
+	"time"
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"encoding/binary"
+	"github.com/clarkk/go-util/serv"
+	"github.com/clarkk/go-util/sess"
+)
+
+const test_key = "dGhlIHNhbXBsZSBub25jZQ=="
+
+func Test_websocket(t *testing.T){
+	sess.Init_CSRF("csrf", "app.domain.com")
+	
+	closed := make(chan error, 1)
+	echo := Handler(func(c *Conn, r *http.Request){
+		for {
+			typ, msg, err := c.Read_message()
+			if err != nil {
+				closed <- err
+				return
+			}
+			if err := c.Write_message(typ, msg); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}, Options{
+		Max_message_size:	16,
+		Read_timeout:		200 * time.Millisecond,
+		Subprotocols:		[]string{"v2.chat", "v1.chat"},
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request){
+		echo(serv.NewWriter(w), r)
+	}))
+	defer srv.Close()
+	
+	t.Run("handshake", func(t *testing.T){
+		for origin, want_status := range map[string]int{
+			"https://app.domain.com":	http.StatusSwitchingProtocols,
+			"https://evil.com":			http.StatusForbidden,
+			"null":						http.StatusForbidden,
+		} {
+			conn, _, res := test_dial(t, srv.URL, origin, "13")
+			conn.Close()
+			if res.StatusCode != want_status {
+				t.Fatalf("Origin %s: status want %d but got %d", origin, want_status, res.StatusCode)
+			}
+			if want_status == http.StatusSwitchingProtocols {
+				test_header(t, res, "Sec-WebSocket-Accept", "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+				test_header(t, res, "Sec-WebSocket-Protocol", "v1.chat")
+				<-closed
+			}
+		}
+		
+		conn, _, res := test_dial(t, srv.URL, "https://app.domain.com", "8")
+		conn.Close()
+		if res.StatusCode != http.StatusUpgradeRequired {
+			t.Fatalf("Version status want 426 but got %d", res.StatusCode)
+		}
+		test_header(t, res, "Sec-WebSocket-Version", "13")
+	})
+	
+	t.Run("messages", func(t *testing.T){
+		conn, br, _ := test_dial(t, srv.URL, "https://app.domain.com", "13")
+		defer conn.Close()
+		
+		//	Fragmented text message with ping in between
+		test_write_frame(t, conn, false, op_text, []byte("hello "))
+		test_write_frame(t, conn, true, op_ping, []byte("p"))
+		test_write_frame(t, conn, true, op_continuation, []byte("world"))
+		test_read_frame(t, br, op_pong, "p")
+		test_read_frame(t, br, op_text, "hello world")
+		
+		test_write_frame(t, conn, true, op_binary, []byte{0, 1, 2})
+		test_read_frame(t, br, op_binary, "\x00\x01\x02")
+		
+		test_write_frame(t, conn, true, op_close, binary.BigEndian.AppendUint16(nil, CLOSE_GOING_AWAY))
+		test_read_frame(t, br, op_close, "\x03\xe9")
+		test_closed(t, closed, CLOSE_GOING_AWAY)
+	})
+	
+	t.Run("violations", func(t *testing.T){
+		for name, test := range map[string]struct{
+			opcode	byte
+			payload	[]byte
+			code	int
+		}{
+			"too large":		{op_binary, make([]byte, 17), CLOSE_TOO_LARGE},
+			"invalid UTF-8":	{op_text, []byte{0xff, 0xfe}, CLOSE_INVALID_DATA},
+			"continuation":		{op_continuation, []byte("x"), CLOSE_PROTOCOL_ERROR},
+			"close code":		{op_close, binary.BigEndian.AppendUint16(nil, 1005), CLOSE_PROTOCOL_ERROR},
+		} {
+			conn, br, _ := test_dial(t, srv.URL, "https://app.domain.com", "13")
+			test_write_frame(t, conn, true, test.opcode, test.payload)
+			opcode, payload := test_frame(t, br)
+			conn.Close()
+			if opcode != op_close || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != test.code {
+				t.Fatalf("%s: want close %d but got opcode %d %q", name, test.code, opcode, payload)
+			}
+			test_closed(t, closed, test.code)
+		}
+	})
+	
+	t.Run("read timeout", func(t *testing.T){
+		conn, br, _ := test_dial(t, srv.URL, "https://app.domain.com", "13")
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := br.ReadByte(); err == nil {
+			t.Fatal("Connection want closed after read timeout")
+		}
+		var net_err net.Error
+		if err := <-closed; !errors.As(err, &net_err) || !net_err.Timeout() {
+			t.Fatalf("Handler want timeout error but got %v", err)
+		}
+	})
+}
+
+func test_dial(t *testing.T, url, origin, version string) (net.Conn, *bufio.Reader, *http.Response){
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	r, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Sec-WebSocket-Key", test_key)
+	r.Header.Set("Sec-WebSocket-Version", version)
+	r.Header.Set("Sec-WebSocket-Protocol", "v1.chat, v3.chat")
+	if err := r.Write(conn); err != nil {
+		t.Fatalf("Failed to send handshake: %s", err)
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, r)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %s", err)
+	}
+	return conn, br, res
+}
+
+func test_write_frame(t *testing.T, conn net.Conn, fin bool, opcode byte, payload []byte){
+	mask := [4]byte{1, 2, 3, 4}
+	b := []byte{opcode, bit_mask | byte(len(payload))}
+	if fin {
+		b[0] |= bit_fin
+	}
+	b = append(b, mask[:]...)
+	for i, c := range payload {
+		b = append(b, c ^ mask[i & 3])
+	}
+	if _, err := conn.Write(b); err != nil {
+		t.Fatalf("Failed to write frame: %s", err)
+	}
+}
+
+func test_frame(t *testing.T, br *bufio.Reader) (byte, []byte){
+	var h [2]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil {
+		t.Fatalf("Failed to read frame: %s", err)
+	}
+	payload := make([]byte, h[1] & 0x7F)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("Failed to read payload: %s", err)
+	}
+	return h[0] & 0x0F, payload
+}
+
+func test_read_frame(t *testing.T, br *bufio.Reader, want_opcode byte, want string){
+	opcode, payload := test_frame(t, br)
+	if opcode != want_opcode || !bytes.Equal(payload, []byte(want)) {
+		t.Fatalf("Frame want opcode %d %q but got %d %q", want_opcode, want, opcode, payload)
+	}
+}
+
+func test_closed(t *testing.T, closed <-chan error, want_code int){
+	var e *Close_error
+	if err := <-closed; !errors.As(err, &e) || e.Code != want_code {
+		t.Fatalf("Handler want close %d but got %v", want_code, err)
+	}
+}
+
+func test_header(t *testing.T, res *http.Response, key, want string){
+	if got := res.Header.Get(key); got != want {
+		t.Fatalf("Header %s want [%s] but got [%s]", key, want, got)
+	}
+}
\ No newline at end of file
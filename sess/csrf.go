@@ -35,6 +35,11 @@ func Verify_CSRF(r *http.Request) bool {
 		return false
 	}
 	
+	return Verify_origin(r)
+}
+
+//	Verify that the Origin or Referer header matches the CSRF origin (e.g. before WebSocket upgrades). Fails if Init_CSRF was not called
+func Verify_origin(r *http.Request) bool {
 	if verify_origin(r.Header.Get("Origin")) {
 		return true
 	}
@@ -62,11 +67,11 @@ func (s *Session) generate_CSRF() (token string){
 }
 
 func verify_origin(header_url string) bool {
-	if header_url == "" {
+	if csrf_origin == "" || header_url == "" {
 		return false
 	}
 	parsed_url, err := url.Parse(header_url)
-	if err != nil {
+	if err != nil || parsed_url.Host == "" {
 		return false
 	}
 	return csrf_origin == parsed_url.Host
@@ -0,0 +1,38 @@
+package sess
+
+import (
+	"testing"
+	"net/http"
+)
+
+func Test_verify_origin(t *testing.T){
+	defer Init_CSRF("", "")
+	
+	request := func(origin string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, "https://app.domain.com/ws", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		r.Header.Set("Origin", origin)
+		return r
+	}
+	
+	Init_CSRF("", "")
+	for _, origin := range []string{"null", "https://app.domain.com", "/relative"} {
+		if Verify_origin(request(origin)) {
+			t.Fatalf("Origin %s want rejected without CSRF origin", origin)
+		}
+	}
+	
+	Init_CSRF("csrf", "app.domain.com")
+	for origin, want := range map[string]bool{
+		"https://app.domain.com":	true,
+		"https://evil.com":			false,
+		"null":						false,
+		"":							false,
+	} {
+		if got := Verify_origin(request(origin)); got != want {
+			t.Fatalf("Origin %s want %t but got %t", origin, want, got)
+		}
+	}
+}
\ No newline at end of file